@@ -0,0 +1,213 @@
+// Package address encodes, decodes and validates Alephium addresses offline.
+//
+// An address is the base58 encoding of a serialized lockup script: a type byte followed by
+// the hash of a public key (P2PKH), the hashes of several public keys and the number of
+// signatures required (P2MPKH), or the hash of a script (P2SH). Unlike Bitcoin's, the encoding
+// has no checksum, so an address is valid when its structure is, without any byte left over.
+package address
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/touilleio/alephium-go-client/serde"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Type is the type of the lockup script of an address
+type Type byte
+
+const (
+	P2PKH  Type = 0x00
+	P2MPKH Type = 0x01
+	P2SH   Type = 0x02
+)
+
+const HashLength = 32
+
+var (
+	ErrEmpty       = errors.New("empty address")
+	ErrUnknownType = errors.New("unknown address type")
+)
+
+func (t Type) String() string {
+	switch t {
+	case P2PKH:
+		return "P2PKH"
+	case P2MPKH:
+		return "P2MPKH"
+	case P2SH:
+		return "P2SH"
+	default:
+		return fmt.Sprintf("Type(%d)", byte(t))
+	}
+}
+
+// Address is a decoded address
+type Address struct {
+	kind Type
+	// hashes are the public key hashes, or the script hash for P2SH
+	hashes [][]byte
+	// m is the number of signatures required by a P2MPKH address
+	m int
+}
+
+// NewP2PKH creates the address locked by the given compressed public key
+func NewP2PKH(publicKey []byte) Address {
+	hash := blake2b.Sum256(publicKey)
+	return Address{kind: P2PKH, hashes: [][]byte{hash[:]}}
+}
+
+// NewP2MPKH creates the address locked by m signatures of the given compressed public keys
+func NewP2MPKH(publicKeys [][]byte, m int) (Address, error) {
+	if m <= 0 || m > len(publicKeys) {
+		return Address{}, fmt.Errorf("invalid P2MPKH address, %d signatures required for %d public keys", m, len(publicKeys))
+	}
+	hashes := make([][]byte, len(publicKeys))
+	for i, publicKey := range publicKeys {
+		hash := blake2b.Sum256(publicKey)
+		hashes[i] = hash[:]
+	}
+	return Address{kind: P2MPKH, hashes: hashes, m: m}, nil
+}
+
+// NewP2SH creates the address locked by the script of the given hash
+func NewP2SH(scriptHash []byte) (Address, error) {
+	if len(scriptHash) != HashLength {
+		return Address{}, fmt.Errorf("invalid script hash length %d, expected %d", len(scriptHash), HashLength)
+	}
+	return Address{kind: P2SH, hashes: [][]byte{append([]byte(nil), scriptHash...)}}, nil
+}
+
+// Decode decodes and validates a base58 address
+func Decode(s string) (Address, error) {
+	if s == "" {
+		return Address{}, ErrEmpty
+	}
+	b, err := Base58Decode(s)
+	if err != nil {
+		return Address{}, err
+	}
+	return FromBytes(b)
+}
+
+// Validate checks that the string is a valid address
+func Validate(s string) error {
+	_, err := Decode(s)
+	return err
+}
+
+// FromBytes decodes a serialized lockup script
+func FromBytes(b []byte) (Address, error) {
+	address, rest, err := DecodeLockupScript(b)
+	if err != nil {
+		return Address{}, err
+	}
+	if len(rest) != 0 {
+		return Address{}, fmt.Errorf("invalid %s address, %d trailing bytes", address.kind, len(rest))
+	}
+	return address, nil
+}
+
+// DecodeLockupScript decodes the lockup script at the beginning of b, and returns the bytes left
+func DecodeLockupScript(b []byte) (Address, []byte, error) {
+	if len(b) == 0 {
+		return Address{}, nil, ErrEmpty
+	}
+	address := Address{kind: Type(b[0])}
+	rest := b[1:]
+	switch address.kind {
+	case P2PKH, P2SH:
+		if len(rest) < HashLength {
+			return Address{}, nil, fmt.Errorf("invalid %s address, %d bytes instead of %d", address.kind, len(rest), HashLength)
+		}
+		address.hashes = [][]byte{rest[:HashLength]}
+		return address, rest[HashLength:], nil
+	case P2MPKH:
+		n, rest, err := serde.DecodeI32(rest)
+		if err != nil {
+			return Address{}, nil, fmt.Errorf("invalid P2MPKH address: %v", err)
+		}
+		if n <= 0 || len(rest) < int(n)*HashLength {
+			return Address{}, nil, fmt.Errorf("invalid P2MPKH address, %d public key hashes", n)
+		}
+		for i := 0; i < int(n); i++ {
+			address.hashes = append(address.hashes, rest[:HashLength])
+			rest = rest[HashLength:]
+		}
+		m, rest, err := serde.DecodeI32(rest)
+		if err != nil {
+			return Address{}, nil, fmt.Errorf("invalid P2MPKH address: %v", err)
+		}
+		if m <= 0 || int(m) > len(address.hashes) {
+			return Address{}, nil, fmt.Errorf("invalid P2MPKH address, %d signatures required for %d public keys", m, n)
+		}
+		address.m = int(m)
+		return address, rest, nil
+	default:
+		return Address{}, nil, fmt.Errorf("%w %d", ErrUnknownType, b[0])
+	}
+}
+
+// Type returns the type of the lockup script
+func (a Address) Type() Type {
+	return a.kind
+}
+
+// Hashes returns the public key hashes of a P2PKH or P2MPKH address, or the script hash of a P2SH address
+func (a Address) Hashes() [][]byte {
+	return a.hashes
+}
+
+// RequiredSignatures returns the number of signatures required by a P2MPKH address, 1 otherwise
+func (a Address) RequiredSignatures() int {
+	if a.kind == P2MPKH {
+		return a.m
+	}
+	return 1
+}
+
+// Bytes serializes the lockup script
+func (a Address) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(byte(a.kind))
+	if a.kind == P2MPKH {
+		buf.Write(serde.EncodeI32(int32(len(a.hashes))))
+	}
+	for _, hash := range a.hashes {
+		buf.Write(hash)
+	}
+	if a.kind == P2MPKH {
+		buf.Write(serde.EncodeI32(int32(a.m)))
+	}
+	return buf.Bytes()
+}
+
+// String encodes the address in base58
+func (a Address) String() string {
+	return Base58Encode(a.Bytes())
+}
+
+// ScriptHint is the hint of the lockup script, used by the inputs spending its outputs
+func (a Address) ScriptHint() uint32 {
+	if len(a.hashes) == 0 {
+		return 0
+	}
+	return djbHash(a.hashes[0]) | 1
+}
+
+// Group computes the group of the address, for a clique with the given number of groups
+func (a Address) Group(groups int) int {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, a.ScriptHint())
+	return int(b[0]^b[1]^b[2]^b[3]) % groups
+}
+
+func djbHash(b []byte) uint32 {
+	hash := uint32(5381)
+	for _, c := range b {
+		hash = (hash << 5) + hash + uint32(c)
+	}
+	return hash
+}
@@ -0,0 +1,104 @@
+package address
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// genesis addresses of user-dev-standalone.conf, one per group
+var genesisAddresses = []string{
+	"1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi",
+	"1Ambgi1jNRcBcdDUSfyrY2uQXdHpJs3zfc7Nmt6NcpBbL",
+	"1C5B3hMC9qu5s4JSmxtNbqEjKScoJRsbDtjwyFCcfELYw",
+	"18KzLirQvNQDh7J4Pu2QBxBwcerwJ9dELfh7QV7BNLfQa",
+}
+
+func TestDecode(t *testing.T) {
+
+	for group, s := range genesisAddresses {
+		a, err := Decode(s)
+		assert.Nil(t, err)
+		assert.Equal(t, P2PKH, a.Type())
+		assert.Equal(t, 1, len(a.Hashes()))
+		assert.Equal(t, group, a.Group(4))
+		assert.Equal(t, s, a.String())
+	}
+
+	_, err := Decode("")
+	assert.True(t, errors.Is(err, ErrEmpty))
+	// 0 and l are not in the base58 alphabet
+	_, err = Decode("1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVV0")
+	assert.NotNil(t, err)
+	// truncated hash
+	_, err = Decode("1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3Yvg")
+	assert.NotNil(t, err)
+	_, err = Decode(Base58Encode(append([]byte{0x09}, make([]byte, 32)...)))
+	assert.True(t, errors.Is(err, ErrUnknownType))
+}
+
+func TestP2PKH(t *testing.T) {
+
+	publicKey, _ := hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	a := NewP2PKH(publicKey)
+	decoded, err := Decode(a.String())
+	assert.Nil(t, err)
+	assert.Equal(t, a.Bytes(), decoded.Bytes())
+	assert.Equal(t, 1, decoded.RequiredSignatures())
+}
+
+func TestP2MPKH(t *testing.T) {
+
+	keys := [][]byte{
+		bytes.Repeat([]byte{2}, 33),
+		bytes.Repeat([]byte{3}, 33),
+		bytes.Repeat([]byte{4}, 33),
+	}
+	a, err := NewP2MPKH(keys, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, P2MPKH, a.Type())
+
+	decoded, err := Decode(a.String())
+	assert.Nil(t, err)
+	assert.Equal(t, P2MPKH, decoded.Type())
+	assert.Equal(t, 3, len(decoded.Hashes()))
+	assert.Equal(t, 2, decoded.RequiredSignatures())
+	assert.Equal(t, a.String(), decoded.String())
+	// the group is the one of the first public key
+	assert.Equal(t, NewP2PKH(keys[0]).Group(4), decoded.Group(4))
+
+	_, err = NewP2MPKH(keys, 4)
+	assert.NotNil(t, err)
+	// trailing byte
+	_, err = FromBytes(append(a.Bytes(), 0))
+	assert.NotNil(t, err)
+	// more signatures required than keys
+	b := a.Bytes()
+	b[len(b)-1] = 4
+	_, err = FromBytes(b)
+	assert.NotNil(t, err)
+}
+
+func TestP2SH(t *testing.T) {
+
+	a, err := NewP2SH(bytes.Repeat([]byte{1}, 32))
+	assert.Nil(t, err)
+	decoded, err := Decode(a.String())
+	assert.Nil(t, err)
+	assert.Equal(t, P2SH, decoded.Type())
+	assert.Equal(t, a.Hashes(), decoded.Hashes())
+
+	_, err = NewP2SH([]byte{1})
+	assert.NotNil(t, err)
+}
+
+func TestBase58(t *testing.T) {
+
+	b, err := Base58Decode("1112")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 1}, b)
+	assert.Equal(t, "1112", Base58Encode(b))
+	assert.Equal(t, "", Base58Encode(nil))
+}
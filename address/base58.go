@@ -0,0 +1,64 @@
+package address
+
+import (
+	"fmt"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Indexes [256]int
+
+func init() {
+	for i := range base58Indexes {
+		base58Indexes[i] = -1
+	}
+	for i, c := range base58Alphabet {
+		base58Indexes[c] = i
+	}
+}
+
+// Base58Encode encodes the bytes in base58, with the bitcoin alphabet
+func Base58Encode(b []byte) string {
+	x := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	out := make([]byte, 0, len(b)*138/100+1)
+	for x.Sign() > 0 {
+		x.DivMod(x, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	reverse(out)
+	return string(out)
+}
+
+// Base58Decode decodes a base58 string, with the bitcoin alphabet
+func Base58Decode(s string) ([]byte, error) {
+	x := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		index := base58Indexes[s[i]]
+		if index < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q at position %d", s[i], i)
+		}
+		x.Mul(x, radix)
+		x.Add(x, big.NewInt(int64(index)))
+	}
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), x.Bytes()...), nil
+}
+
+func reverse(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
@@ -1,9 +1,12 @@
 package alephium
 
 import (
+	"context"
+	"fmt"
 	"github.com/dghubble/sling"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -12,36 +15,109 @@ type Client struct {
 	apiToken    string
 	oldClient   *http.Client
 	slingClient *sling.Sling
-	log         *logrus.Logger
+	log         Logger
 	sleepTime   time.Duration
+	retryPolicy RetryPolicy
 }
 
 const (
-	ApiKeyHeader = "X-API-KEY"
+	ApiKeyHeader    = "X-API-KEY"
+	UserAgentHeader = "User-Agent"
+
+	DefaultTimeout      = 30 * time.Second
+	DefaultPollInterval = 5 * time.Second
 )
 
+// Logger is the logging interface used by the Client. *logrus.Logger and *logrus.Entry implement it.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// NopLogger is a Logger discarding everything, used when none is configured
+type NopLogger struct{}
+
+func (NopLogger) Debugf(format string, args ...interface{}) {}
+func (NopLogger) Infof(format string, args ...interface{})  {}
+func (NopLogger) Warnf(format string, args ...interface{})  {}
+func (NopLogger) Errorf(format string, args ...interface{}) {}
+
 func New(alephiumEndpoint string, log *logrus.Logger) (*Client, error) {
 	return NewWithApiKey(alephiumEndpoint, "", log)
 }
 
 func NewWithApiKey(alephiumEndpoint string, apiKey string, log *logrus.Logger) (*Client, error) {
+	opts := []Option{WithApiKey(apiKey)}
+	if log != nil {
+		opts = append(opts, WithLogger(log))
+	}
+	return NewClient(alephiumEndpoint, opts...)
+}
+
+// NewClient creates a new client for the given endpoint, configured with the given options.
+func NewClient(alephiumEndpoint string, opts ...Option) (*Client, error) {
+
+	if _, err := url.ParseRequestURI(alephiumEndpoint); err != nil {
+		return nil, fmt.Errorf("invalid endpoint %s: %v", alephiumEndpoint, err)
+	}
+
+	config := &clientConfig{
+		pollInterval: DefaultPollInterval,
+		headers:      make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
 
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	var client *http.Client
+	if config.httpClient != nil {
+		c := *config.httpClient
+		client = &c
+		if config.timeout > 0 {
+			client.Timeout = config.timeout
+		}
+	} else {
+		timeout := config.timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		client = &http.Client{
+			Timeout: timeout,
+		}
+	}
+	if config.transport != nil {
+		client.Transport = config.transport
 	}
 
 	slingClient := sling.New().Client(client).Base(alephiumEndpoint)
 
-	if apiKey != "" {
-		slingClient = slingClient.Add(ApiKeyHeader, apiKey)
+	for key, values := range config.headers {
+		for _, value := range values {
+			slingClient = slingClient.Add(key, value)
+		}
+	}
+	if config.userAgent != "" {
+		slingClient = slingClient.Set(UserAgentHeader, config.userAgent)
+	}
+	if config.apiKey != "" {
+		slingClient = slingClient.Set(ApiKeyHeader, config.apiKey)
+	}
+
+	log := config.log
+	if log == nil {
+		log = NopLogger{}
 	}
 
 	alephiumClient := &Client{
 		endpointURI: alephiumEndpoint,
+		apiToken:    config.apiKey,
 		oldClient:   client,
 		slingClient: slingClient,
 		log:         log,
-		sleepTime:   5 * time.Second,
+		sleepTime:   config.pollInterval,
+		retryPolicy: config.retryPolicy,
 	}
 
 	return alephiumClient, nil
@@ -51,11 +127,62 @@ func (a *Client) String() string {
 	return a.endpointURI
 }
 
-func relevantError(e1 error, e2 ErrorDetail) error {
-	if e1 != nil {
-		return e1
-	} else if e2.Detail != "" {
-		return e2
+// receive sends the request built by s bound to ctx, decoding the response
+// into successV, or into an *APIError if the node returned an error.
+// Failed attempts are retried according to the retry policy of the client.
+func (a *Client) receive(ctx context.Context, s *sling.Sling, successV interface{}) error {
+	for attempt := 1; ; attempt++ {
+		req, err := s.Request()
+		if err != nil {
+			return err
+		}
+		var errorDetail ErrorDetail
+		resp, err := s.Do(req.WithContext(ctx), successV, &errorDetail)
+		err = relevantError(resp, err, errorDetail)
+		if err == nil {
+			return nil
+		}
+		backoff, retry := a.retryPolicy.shouldRetry(ctx, req, resp, err, attempt)
+		if !retry {
+			return err
+		}
+		if a.retryPolicy.OnRetry != nil {
+			a.retryPolicy.OnRetry(RetryEvent{
+				Method:  req.Method,
+				Path:    req.URL.Path,
+				Attempt: attempt,
+				Err:     err,
+				Backoff: backoff,
+			})
+		}
+		a.log.Debugf("%s %s failed (attempt %d): %v, retrying in %s", req.Method, req.URL.Path, attempt, err, backoff)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// relevantError returns an *APIError for any non-2xx response, even without body,
+// or the transport error otherwise.
+func relevantError(resp *http.Response, err error, errorDetail ErrorDetail) error {
+	if resp != nil && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return &APIError{StatusCode: resp.StatusCode, ErrorDetail: errorDetail}
+	}
+	return err
+}
+
+// sleep waits for the given duration, or until the context is done, returning its error then
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
-	return nil
 }
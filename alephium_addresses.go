@@ -1,54 +1,55 @@
 package alephium
 
+import (
+	"context"
+)
+
 // GetAddressBalance returns the balance (computed) of the address
 func (a *Client) GetAddressBalance(address string, utxosLimit int) (AddressUtxoBalance, error) {
+	return a.GetAddressBalanceCtx(context.Background(), address, utxosLimit)
+}
+
+// GetAddressBalanceCtx is like GetAddressBalance, with a context
+func (a *Client) GetAddressBalanceCtx(ctx context.Context, address string, utxosLimit int) (AddressUtxoBalance, error) {
 	params := &UtxosLimit{}
 	if utxosLimit > 0 {
 		params.UtxosLimit = utxosLimit
 	}
 	var addressBalance AddressUtxoBalance
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("addresses/"+address+"/balance").
-		QueryStruct(params).Receive(&addressBalance, &errorDetail)
-	return addressBalance, relevantError(err, errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("addresses/"+address+"/balance").QueryStruct(params), &addressBalance)
+	return addressBalance, err
 }
 
 // GetAddressGroup returns the group of the address
 func (a *Client) GetAddressGroup(address string) (AddressGroup, error) {
+	return a.GetAddressGroupCtx(context.Background(), address)
+}
+
+// GetAddressGroupCtx is like GetAddressGroup, with a context
+func (a *Client) GetAddressGroupCtx(ctx context.Context, address string) (AddressGroup, error) {
 	var addressGroup AddressGroup
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("addresses/"+address+"/group").
-		Receive(&addressGroup, &errorDetail)
-	return addressGroup, relevantError(err, errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("addresses/"+address+"/group"), &addressGroup)
+	return addressGroup, err
 }
 
 type AddressUtxosList struct {
-	Utxos []struct {
-		Ref struct {
-			Hint int    `json:"hint"`
-			Key  string `json:"key"`
-		} `json:"ref"`
-		Amount string `json:"amount"` // ALPH
-		Tokens []struct {
-			Id     string `json:"id"`
-			Amount string `json:"amount"` // ALPH
-		} `json:"tokens"`
-		LockTime       int64  `json:"lockTime"`
-		AdditionalData string `json:"additionalData"`
-	} `json:"utxos"`
+	Utxos []Utxo `json:"utxos"`
 }
 
 // GetAddressUtxos returns the UTXOs of the address
 func (a *Client) GetAddressUtxos(address string, utxosLimit int) (AddressUtxosList, error) {
+	return a.GetAddressUtxosCtx(context.Background(), address, utxosLimit)
+}
+
+// GetAddressUtxosCtx is like GetAddressUtxos, with a context
+func (a *Client) GetAddressUtxosCtx(ctx context.Context, address string, utxosLimit int) (AddressUtxosList, error) {
 	params := &UtxosLimit{}
 	if utxosLimit > 0 {
 		params.UtxosLimit = utxosLimit
 	}
 	var utxosList AddressUtxosList
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("addresses/"+address+"/utxos").
-		QueryStruct(params).Receive(&utxosList, &errorDetail)
-	return utxosList, relevantError(err, errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("addresses/"+address+"/utxos").QueryStruct(params), &utxosList)
+	return utxosList, err
 }
 
 type UtxosLimit struct {
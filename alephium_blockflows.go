@@ -1,25 +1,82 @@
 package alephium
 
 import (
-	"fmt"
+	"context"
 )
 
-// GetBlockflows
-func (a *Client) GetBlockflows() error {
-	return fmt.Errorf("not implemented yet")
+type BlockflowRequestParams struct {
+	FromTs int64 `url:"fromTs"`
+	ToTs   int64 `url:"toTs"`
 }
 
-// GetBlockflowByHash
-func (a *Client) GetBlockflowByHash(hash string) error {
-	return fmt.Errorf("not implemented yet")
+// GetBlockflows returns the blocks with a timestamp, in milliseconds, between fromTs and toTs
+func (a *Client) GetBlockflows(fromTs int64, toTs int64) (FetchResponse, error) {
+	return a.GetBlockflowsCtx(context.Background(), fromTs, toTs)
 }
 
-// GetBlockflowByHash
-func (a *Client) GetBlockflowHashesByGroup(group int, height int) error {
-	return fmt.Errorf("not implemented yet")
+// GetBlockflowsCtx is like GetBlockflows, with a context
+func (a *Client) GetBlockflowsCtx(ctx context.Context, fromTs int64, toTs int64) (FetchResponse, error) {
+	var fetchResponse FetchResponse
+	params := BlockflowRequestParams{
+		FromTs: fromTs,
+		ToTs:   toTs,
+	}
+	err := a.receive(ctx, a.slingClient.New().Get("blockflow").QueryStruct(params), &fetchResponse)
+	return fetchResponse, err
 }
 
-// GetBlockflowChains
-func (a *Client) GetBlockflowChains(fromGroup int, toGroup int) error {
-	return fmt.Errorf("not implemented yet")
+// GetBlockflowByHash returns the block with the given hash
+func (a *Client) GetBlockflowByHash(hash string) (BlockEntry, error) {
+	return a.GetBlockflowByHashCtx(context.Background(), hash)
+}
+
+// GetBlockflowByHashCtx is like GetBlockflowByHash, with a context
+func (a *Client) GetBlockflowByHashCtx(ctx context.Context, hash string) (BlockEntry, error) {
+	var blockEntry BlockEntry
+	err := a.receive(ctx, a.slingClient.New().Get("blockflow/blocks/"+hash), &blockEntry)
+	return blockEntry, err
+}
+
+type BlockflowHashesRequestParams struct {
+	FromGroup int `url:"fromGroup"`
+	ToGroup   int `url:"toGroup"`
+	Height    int `url:"height"`
+}
+
+// GetBlockflowHashesByGroup returns the hashes of the blocks of the chain fromGroup -> toGroup at the given height
+func (a *Client) GetBlockflowHashesByGroup(fromGroup int, toGroup int, height int) (HashesAtHeight, error) {
+	return a.GetBlockflowHashesByGroupCtx(context.Background(), fromGroup, toGroup, height)
+}
+
+// GetBlockflowHashesByGroupCtx is like GetBlockflowHashesByGroup, with a context
+func (a *Client) GetBlockflowHashesByGroupCtx(ctx context.Context, fromGroup int, toGroup int, height int) (HashesAtHeight, error) {
+	var hashesAtHeight HashesAtHeight
+	params := BlockflowHashesRequestParams{
+		FromGroup: fromGroup,
+		ToGroup:   toGroup,
+		Height:    height,
+	}
+	err := a.receive(ctx, a.slingClient.New().Get("blockflow/hashes").QueryStruct(params), &hashesAtHeight)
+	return hashesAtHeight, err
+}
+
+type ChainRequestParams struct {
+	FromGroup int `url:"fromGroup"`
+	ToGroup   int `url:"toGroup"`
+}
+
+// GetBlockflowChains returns the info, i.e. the current height, of the chain fromGroup -> toGroup
+func (a *Client) GetBlockflowChains(fromGroup int, toGroup int) (ChainInfo, error) {
+	return a.GetBlockflowChainsCtx(context.Background(), fromGroup, toGroup)
+}
+
+// GetBlockflowChainsCtx is like GetBlockflowChains, with a context
+func (a *Client) GetBlockflowChainsCtx(ctx context.Context, fromGroup int, toGroup int) (ChainInfo, error) {
+	var chainInfo ChainInfo
+	params := ChainRequestParams{
+		FromGroup: fromGroup,
+		ToGroup:   toGroup,
+	}
+	err := a.receive(ctx, a.slingClient.New().Get("blockflow/chains").QueryStruct(params), &chainInfo)
+	return chainInfo, err
 }
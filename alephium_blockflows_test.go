@@ -0,0 +1,67 @@
+package alephium
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var testBlockEntry = `{"hash":"bdaf9dc514ce7d34b6474b8ca10a3dfb93ba997cb9d5ff1ea724ebe2af48abe5","timestamp":1611041396892,"chainFrom":1,"chainTo":2,"height":42,
+"deps":["bdaf9dc514ce7d34b6474b8ca10a3dfb93ba997cb9d5ff1ea724ebe2af48abe5"],
+"transactions":[{"id":"503bfb16230888af4924aa8f8250d7d348b862e267d75d3147f1998050b6da69",
+"inputs":[{"outputRef":{"scriptHint":23412,"key":"798e9e137aec7c2d59d9655b4ffa640f301f628bf7c365083bb255f6aa5f89ef"},"unlockScript":"00d1b70d2226308b46da297486adb6b4f1a8c1842cb159ac5ec04f384fe2d6f5da28"}],
+"outputs":[{"amount":"1000000000000000000","address":"M1AujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n","lockTime":1611041396892}]}]}`
+
+func TestBlockflows(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		q := r.URL.Query()
+		switch r.URL.Path {
+		case "/blockflow":
+			assert.Equal(t, "1611041396000", q.Get("fromTs"))
+			assert.Equal(t, "1611041397000", q.Get("toTs"))
+			_, _ = w.Write([]byte(`{"blocks":[` + testBlockEntry + `]}`))
+		case "/blockflow/blocks/bdaf9dc514ce7d34b6474b8ca10a3dfb93ba997cb9d5ff1ea724ebe2af48abe5":
+			_, _ = w.Write([]byte(testBlockEntry))
+		case "/blockflow/hashes":
+			assert.Equal(t, "1", q.Get("fromGroup"))
+			assert.Equal(t, "2", q.Get("toGroup"))
+			assert.Equal(t, "42", q.Get("height"))
+			_, _ = w.Write([]byte(`{"headers":["bdaf9dc514ce7d34b6474b8ca10a3dfb93ba997cb9d5ff1ea724ebe2af48abe5"]}`))
+		case "/blockflow/chains":
+			assert.Equal(t, "1", q.Get("fromGroup"))
+			assert.Equal(t, "2", q.Get("toGroup"))
+			_, _ = w.Write([]byte(`{"currentHeight":42}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	alephiumClient, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	blockflows, err := alephiumClient.GetBlockflows(1611041396000, 1611041397000)
+	assert.Nil(t, err)
+	assert.Len(t, blockflows.Blocks, 1)
+
+	block, err := alephiumClient.GetBlockflowByHash("bdaf9dc514ce7d34b6474b8ca10a3dfb93ba997cb9d5ff1ea724ebe2af48abe5")
+	assert.Nil(t, err)
+	assert.Equal(t, blockflows.Blocks[0], block)
+	assert.Equal(t, 42, block.Height)
+	assert.Equal(t, 1, block.ChainFrom)
+	assert.Equal(t, 2, block.ChainTo)
+	assert.Len(t, block.Transactions, 1)
+	assert.Equal(t, 23412, block.Transactions[0].Inputs[0].OutputRef.ScriptHint)
+	assert.Equal(t, "1000000000000000000", block.Transactions[0].Outputs[0].Amount.String())
+
+	hashes, err := alephiumClient.GetBlockflowHashesByGroup(1, 2, 42)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{block.Hash}, hashes.Headers)
+
+	chainInfo, err := alephiumClient.GetBlockflowChains(1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 42, chainInfo.CurrentHeight)
+}
@@ -0,0 +1,317 @@
+package alephium
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultReorgDepth is the number of blocks checked for reorgs on every chain by a ChainFollower
+	DefaultReorgDepth = 20
+	// DefaultMaxBlocksPerPoll is the maximum number of blocks connected per chain by a poll of a ChainFollower
+	DefaultMaxBlocksPerPoll = 100
+)
+
+// ChainEventKind is the kind of a ChainEvent
+type ChainEventKind int
+
+const (
+	// BlockConnected is fired when a block is added to the main chain
+	BlockConnected ChainEventKind = iota + 1
+	// BlockDisconnected is fired when a block is removed from the main chain by a reorg
+	BlockDisconnected
+)
+
+func (k ChainEventKind) String() string {
+	switch k {
+	case BlockConnected:
+		return "connected"
+	case BlockDisconnected:
+		return "disconnected"
+	}
+	return "unknown"
+}
+
+// ChainEvent is a change of the main chain from a group to a group
+type ChainEvent struct {
+	Kind      ChainEventKind
+	FromGroup int
+	ToGroup   int
+	Height    int
+	Hash      string
+	// Block is the block connected, if the follower fetches them
+	Block *BlockEntry
+}
+
+// ChainCursor is the position of a ChainFollower on the chain from a group to a group
+type ChainCursor struct {
+	FromGroup int `json:"fromGroup"`
+	ToGroup   int `json:"toGroup"`
+	// Height is the height of the last block connected
+	Height int `json:"height"`
+	// CurrentHeight is the height of the chain at the last poll
+	CurrentHeight int `json:"currentHeight"`
+	// Hashes are the hashes of the last blocks connected, by height, to detect reorgs
+	Hashes map[int]string `json:"hashes"`
+}
+
+func (c ChainCursor) copy() ChainCursor {
+	hashes := make(map[int]string, len(c.Hashes))
+	for h, hash := range c.Hashes {
+		hashes[h] = hash
+	}
+	c.Hashes = hashes
+	return c
+}
+
+// apply moves the cursor to the block of the event
+func (c *ChainCursor) apply(event ChainEvent) {
+	if event.Kind == BlockDisconnected {
+		delete(c.Hashes, event.Height)
+		c.Height = event.Height - 1
+		return
+	}
+	c.Hashes[event.Height] = event.Hash
+	c.Height = event.Height
+}
+
+// ChainKey is the key of the chain from a group to a group in the cursors of a ChainFollower
+func ChainKey(fromGroup int, toGroup int) string {
+	return fmt.Sprintf("%d-%d", fromGroup, toGroup)
+}
+
+// ChainFollowerConfig configures a ChainFollower
+type ChainFollowerConfig struct {
+	// Groups is the number of groups of the clique, fetched from the node if 0
+	Groups int
+	// PollInterval is the interval between two polls of Run, the poll interval of the client if 0
+	PollInterval time.Duration
+	// ReorgDepth is the number of blocks checked for reorgs on every chain, DefaultReorgDepth if 0
+	ReorgDepth int
+	// StartHeight is the height of the first block connected on the chains without cursor,
+	// the block after the current one if 0
+	StartHeight int
+	// MaxBlocksPerPoll is the maximum number of blocks connected per chain by Poll, DefaultMaxBlocksPerPoll if 0
+	MaxBlocksPerPoll int
+	// FetchBlocks fetches the blocks connected, in the Block of their event
+	FetchBlocks bool
+	// Cursors are the cursors to resume from, by ChainKey, as returned by ChainFollower.Cursors
+	Cursors map[string]ChainCursor
+	// OnEvent, if set, is called with every event, from the polling loop
+	OnEvent func(ChainEvent)
+	// Events, if set, receives every event. The polling loop blocks until they are received.
+	Events chan<- ChainEvent
+}
+
+// ChainFollower follows the groups x groups chains of the blockflow, with the current height of every
+// chain from GetBlockflowChains and the hash of its main chain at every height from GetBlockflowHashesByGroup.
+// When the hash at a height already connected changes, the blocks from that height are disconnected,
+// from the highest down, before the blocks of the new main chain are connected, from the lowest up.
+type ChainFollower struct {
+	client *Client
+	config ChainFollowerConfig
+
+	// mu protects cursors, pollMu makes sure a single poll runs at a time
+	mu      sync.Mutex
+	pollMu  sync.Mutex
+	cursors map[string]ChainCursor
+}
+
+// NewChainFollower creates a ChainFollower polling the node of the client
+func (a *Client) NewChainFollower(config ChainFollowerConfig) *ChainFollower {
+	if config.PollInterval <= 0 {
+		config.PollInterval = a.sleepTime
+	}
+	if config.ReorgDepth <= 0 {
+		config.ReorgDepth = DefaultReorgDepth
+	}
+	if config.MaxBlocksPerPoll <= 0 {
+		config.MaxBlocksPerPoll = DefaultMaxBlocksPerPoll
+	}
+	cursors := make(map[string]ChainCursor, len(config.Cursors))
+	for key, cursor := range config.Cursors {
+		cursors[key] = cursor.copy()
+	}
+	return &ChainFollower{
+		client:  a,
+		config:  config,
+		cursors: cursors,
+	}
+}
+
+// Cursors returns a copy of the cursors of the chains, by ChainKey
+func (f *ChainFollower) Cursors() map[string]ChainCursor {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cursors := make(map[string]ChainCursor, len(f.cursors))
+	for key, cursor := range f.cursors {
+		cursors[key] = cursor.copy()
+	}
+	return cursors
+}
+
+// Run polls the chains until the context is done, returning its error.
+// Failing polls are logged and retried at the next interval.
+func (f *ChainFollower) Run(ctx context.Context) error {
+	for {
+		if err := f.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			f.client.log.Warnf("Failed to follow the chains: %v", err)
+		}
+		if err := sleep(ctx, f.config.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// Poll polls every chain once, in order, and fires the events of their changes
+func (f *ChainFollower) Poll(ctx context.Context) error {
+	f.pollMu.Lock()
+	defer f.pollMu.Unlock()
+
+	if f.config.Groups <= 0 {
+		infos, err := f.client.GetSelfCliqueInfosCtx(ctx)
+		if err != nil {
+			return err
+		}
+		f.config.Groups = infos.Groups
+	}
+	for from := 0; from < f.config.Groups; from++ {
+		for to := 0; to < f.config.Groups; to++ {
+			if err := f.pollChain(ctx, from, to); err != nil {
+				return fmt.Errorf("chain %d -> %d: %w", from, to, err)
+			}
+		}
+	}
+	return nil
+}
+
+// pollChain disconnects the blocks replaced by a reorg, if any, and connects the new ones.
+// The events of the blocks (dis)connected before an error are fired anyway.
+func (f *ChainFollower) pollChain(ctx context.Context, from int, to int) error {
+	chainInfo, err := f.client.GetBlockflowChainsCtx(ctx, from, to)
+	if err != nil {
+		return err
+	}
+
+	key := ChainKey(from, to)
+	f.mu.Lock()
+	cursor, ok := f.cursors[key]
+	f.mu.Unlock()
+	if ok {
+		cursor = cursor.copy()
+	} else {
+		height := chainInfo.CurrentHeight
+		if f.config.StartHeight > 0 {
+			height = f.config.StartHeight - 1
+		}
+		cursor = ChainCursor{FromGroup: from, ToGroup: to, Height: height, Hashes: make(map[int]string)}
+	}
+	cursor.CurrentHeight = chainInfo.CurrentHeight
+	// the cursor stored only moves past the events delivered
+	delivered := cursor.copy()
+
+	events, err := f.followChain(ctx, &cursor)
+
+	for _, event := range events {
+		f.client.log.Debugf("Block %s at height %d of chain %d -> %d %s", event.Hash, event.Height, from, to, event.Kind)
+		if f.config.OnEvent != nil {
+			f.config.OnEvent(event)
+		}
+		if f.config.Events != nil {
+			select {
+			case f.config.Events <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		f.mu.Lock()
+		delivered.apply(event)
+		f.cursors[key] = delivered
+		f.mu.Unlock()
+	}
+
+	f.mu.Lock()
+	f.cursors[key] = cursor
+	f.mu.Unlock()
+	return err
+}
+
+// followChain moves the cursor along the main chain, returning the events of the moves
+func (f *ChainFollower) followChain(ctx context.Context, cursor *ChainCursor) ([]ChainEvent, error) {
+	from, to := cursor.FromGroup, cursor.ToGroup
+	var events []ChainEvent
+
+	// the hashes are checked from the cursor down, until one is still on the main chain
+	fork := cursor.Height + 1
+	for h := cursor.Height; h > cursor.Height-f.config.ReorgDepth; h-- {
+		hash, ok := cursor.Hashes[h]
+		if !ok {
+			break
+		}
+		canonical, err := f.canonicalHash(ctx, from, to, h)
+		if err != nil {
+			return events, err
+		}
+		if canonical == hash {
+			break
+		}
+		fork = h
+	}
+	if fork <= cursor.Height {
+		f.client.log.Infof("Reorg of chain %d -> %d from height %d to %d", from, to, fork, cursor.Height)
+	}
+	for h := cursor.Height; h >= fork; h-- {
+		events = append(events, ChainEvent{Kind: BlockDisconnected, FromGroup: from, ToGroup: to, Height: h, Hash: cursor.Hashes[h]})
+		delete(cursor.Hashes, h)
+		cursor.Height = h - 1
+	}
+
+	end := cursor.CurrentHeight
+	if end > cursor.Height+f.config.MaxBlocksPerPoll {
+		end = cursor.Height + f.config.MaxBlocksPerPoll
+	}
+	for h := cursor.Height + 1; h <= end; h++ {
+		hash, err := f.canonicalHash(ctx, from, to, h)
+		if err != nil {
+			return events, err
+		}
+		if hash == "" {
+			break
+		}
+		event := ChainEvent{Kind: BlockConnected, FromGroup: from, ToGroup: to, Height: h, Hash: hash}
+		if f.config.FetchBlocks {
+			block, err := f.client.GetBlockflowByHashCtx(ctx, hash)
+			if err != nil {
+				return events, err
+			}
+			event.Block = &block
+		}
+		events = append(events, event)
+		cursor.Hashes[h] = hash
+		cursor.Height = h
+	}
+
+	for h := range cursor.Hashes {
+		if h <= cursor.Height-f.config.ReorgDepth {
+			delete(cursor.Hashes, h)
+		}
+	}
+	return events, nil
+}
+
+// canonicalHash returns the hash of the block of the main chain at the height, empty if there is none yet
+func (f *ChainFollower) canonicalHash(ctx context.Context, from int, to int, height int) (string, error) {
+	hashes, err := f.client.GetBlockflowHashesByGroupCtx(ctx, from, to, height)
+	if err != nil {
+		return "", err
+	}
+	if len(hashes.Headers) == 0 {
+		return "", nil
+	}
+	return hashes.Headers[0], nil
+}
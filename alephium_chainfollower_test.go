@@ -0,0 +1,149 @@
+package alephium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestChainFollower(t *testing.T) {
+
+	var mu sync.Mutex
+	// the main chains, by chain key, only 1 -> 0 grows
+	chains := map[string][]string{
+		"0-0": {"g00"}, "0-1": {"g01"}, "1-0": {"g10", "a1", "a2", "a3"}, "1-1": {"g11"},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		query := r.URL.Query()
+		chain := chains[query.Get("fromGroup")+"-"+query.Get("toGroup")]
+		switch {
+		case r.URL.Path == "/blockflow/chains":
+			_ = json.NewEncoder(w).Encode(ChainInfo{CurrentHeight: len(chain) - 1})
+		case r.URL.Path == "/blockflow/hashes":
+			height, _ := strconv.Atoi(query.Get("height"))
+			hashes := HashesAtHeight{Headers: []string{}}
+			if height < len(chain) {
+				hashes.Headers = append(hashes.Headers, chain[height], "uncle")
+			}
+			_ = json.NewEncoder(w).Encode(hashes)
+		case strings.HasPrefix(r.URL.Path, "/blockflow/blocks/"):
+			_ = json.NewEncoder(w).Encode(BlockEntry{Hash: strings.TrimPrefix(r.URL.Path, "/blockflow/blocks/")})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	alephiumClient, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+	var events []string
+	follower := alephiumClient.NewChainFollower(ChainFollowerConfig{
+		Groups:      2,
+		StartHeight: 1,
+		FetchBlocks: true,
+		OnEvent: func(event ChainEvent) {
+			s := fmt.Sprintf("%s %d->%d %d %s", event.Kind, event.FromGroup, event.ToGroup, event.Height, event.Hash)
+			if event.Block != nil {
+				assert.Equal(t, event.Hash, event.Block.Hash)
+			}
+			events = append(events, s)
+		},
+	})
+
+	ctx := context.Background()
+	assert.Nil(t, follower.Poll(ctx))
+	assert.Equal(t, []string{"connected 1->0 1 a1", "connected 1->0 2 a2", "connected 1->0 3 a3"}, events)
+
+	// the blocks from height 2 are replaced
+	mu.Lock()
+	chains["1-0"] = []string{"g10", "a1", "b2", "b3", "b4"}
+	mu.Unlock()
+	events = nil
+	assert.Nil(t, follower.Poll(ctx))
+	assert.Equal(t, []string{
+		"disconnected 1->0 3 a3", "disconnected 1->0 2 a2",
+		"connected 1->0 2 b2", "connected 1->0 3 b3", "connected 1->0 4 b4",
+	}, events)
+
+	cursors := follower.Cursors()
+	assert.Equal(t, 4, len(cursors))
+	assert.Equal(t, 4, cursors[ChainKey(1, 0)].Height)
+	assert.Equal(t, "b2", cursors[ChainKey(1, 0)].Hashes[2])
+
+	// a follower resumed from the cursors only sees the new blocks
+	mu.Lock()
+	chains["1-0"] = append(chains["1-0"], "b5")
+	mu.Unlock()
+	events = nil
+	follower = alephiumClient.NewChainFollower(ChainFollowerConfig{
+		Groups:  2,
+		Cursors: cursors,
+		OnEvent: func(event ChainEvent) {
+			events = append(events, fmt.Sprintf("%s %d->%d %d %s", event.Kind, event.FromGroup, event.ToGroup, event.Height, event.Hash))
+		},
+	})
+	assert.Nil(t, follower.Poll(ctx))
+	assert.Equal(t, []string{"connected 1->0 5 b5"}, events)
+}
+
+func TestChainFollowerCancel(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		chain := []string{"g", "a1", "a2", "a3"}
+		switch r.URL.Path {
+		case "/blockflow/chains":
+			_ = json.NewEncoder(w).Encode(ChainInfo{CurrentHeight: len(chain) - 1})
+		case "/blockflow/hashes":
+			height, _ := strconv.Atoi(r.URL.Query().Get("height"))
+			_ = json.NewEncoder(w).Encode(HashesAtHeight{Headers: []string{chain[height]}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	alephiumClient, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+	events := make(chan ChainEvent)
+	follower := alephiumClient.NewChainFollower(ChainFollowerConfig{
+		Groups:      1,
+		StartHeight: 1,
+		Events:      events,
+	})
+
+	// the poll is cancelled after the first event
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-events
+		cancel()
+	}()
+	assert.True(t, errors.Is(follower.Poll(ctx), context.Canceled))
+	assert.Equal(t, 1, follower.Cursors()[ChainKey(0, 0)].Height)
+
+	// the next poll delivers the events not received
+	var hashes []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range events {
+			hashes = append(hashes, event.Hash)
+		}
+	}()
+	assert.Nil(t, follower.Poll(context.Background()))
+	close(events)
+	<-done
+	assert.Equal(t, []string{"a2", "a3"}, hashes)
+	assert.Equal(t, 3, follower.Cursors()[ChainKey(0, 0)].Height)
+}
@@ -1,20 +1,138 @@
 package alephium
 
 import (
+	"context"
 	"fmt"
 )
 
-// SendContract
-func (a *Client) SendContract() error {
-	return fmt.Errorf("not implemented yet")
+const (
+	ContractTypeScript   = "script"
+	ContractTypeContract = "contract"
+)
+
+type CompileContractRequestBody struct {
+	Address string `json:"address"`
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	State   string `json:"state,omitempty"`
+}
+
+type CompileResult struct {
+	Code string `json:"code"`
+}
+
+// CompileContract compiles the code of a script or a contract (see ContractType* constants)
+// on behalf of the given address, with an optional initial state
+func (a *Client) CompileContract(address string, contractType string, code string, state string) (CompileResult, error) {
+	return a.CompileContractCtx(context.Background(), address, contractType, code, state)
+}
+
+// CompileContractCtx is like CompileContract, with a context
+func (a *Client) CompileContractCtx(ctx context.Context, address string, contractType string, code string, state string) (CompileResult, error) {
+
+	body := CompileContractRequestBody{
+		Address: address,
+		Type:    contractType,
+		Code:    code,
+		State:   state,
+	}
+
+	var compileResult CompileResult
+	err := a.receive(ctx, a.slingClient.New().Post("contracts/compile").BodyJSON(body), &compileResult)
+
+	return compileResult, err
+}
+
+type BuildContractRequestBody struct {
+	FromKey string `json:"fromKey"`
+	Code    string `json:"code"`
+}
+
+type BuildContractResult struct {
+	UnsignedTx string `json:"unsignedTx"`
+	Hash       string `json:"hash"`
+	FromGroup  int    `json:"fromGroup"`
+	ToGroup    int    `json:"toGroup"`
+}
+
+// BuildContract builds an unsigned transaction deploying the compiled code, to be signed by the owner of publicKey
+func (a *Client) BuildContract(publicKey string, compiledCode string) (BuildContractResult, error) {
+	return a.BuildContractCtx(context.Background(), publicKey, compiledCode)
+}
+
+// BuildContractCtx is like BuildContract, with a context
+func (a *Client) BuildContractCtx(ctx context.Context, publicKey string, compiledCode string) (BuildContractResult, error) {
+
+	body := BuildContractRequestBody{
+		FromKey: publicKey,
+		Code:    compiledCode,
+	}
+
+	var buildContractResult BuildContractResult
+	err := a.receive(ctx, a.slingClient.New().Post("contracts/build").BodyJSON(body), &buildContractResult)
+
+	return buildContractResult, err
+}
+
+type SendContractRequestBody struct {
+	Code      string `json:"code"`
+	Tx        string `json:"tx"`
+	Signature string `json:"signature"`
+	FromGroup int    `json:"fromGroup"`
 }
 
-// CompileContract
-func (a *Client) CompileContract() error {
-	return fmt.Errorf("not implemented yet")
+// SendContract submits a previously built and signed contract transaction
+func (a *Client) SendContract(compiledCode string, unsignedTx string, signature string, fromGroup int) (Transaction, error) {
+	return a.SendContractCtx(context.Background(), compiledCode, unsignedTx, signature, fromGroup)
 }
 
-// BuildContract
-func (a *Client) BuildContract() error {
-	return fmt.Errorf("not implemented yet")
+// SendContractCtx is like SendContract, with a context
+func (a *Client) SendContractCtx(ctx context.Context, compiledCode string, unsignedTx string, signature string, fromGroup int) (Transaction, error) {
+
+	body := SendContractRequestBody{
+		Code:      compiledCode,
+		Tx:        unsignedTx,
+		Signature: signature,
+		FromGroup: fromGroup,
+	}
+
+	var tx Transaction
+	err := a.receive(ctx, a.slingClient.New().Post("contracts/send").BodyJSON(body), &tx)
+
+	return tx, err
+}
+
+// DeployContract compiles, builds, signs with the active address of the wallet, submits
+// the contract and waits until the transaction is confirmed or the context is done.
+func (a *Client) DeployContract(ctx context.Context, walletName string, contractType string, code string, state string) (Transaction, error) {
+
+	walletAddresses, err := a.GetWalletAddressesCtx(ctx, walletName)
+	if err != nil {
+		return Transaction{}, err
+	}
+	addressDetail, err := a.GetWalletAddressDetailCtx(ctx, walletName, walletAddresses.ActiveAddress)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	compiled, err := a.CompileContractCtx(ctx, addressDetail.Address, contractType, code, state)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("compile: %w", err)
+	}
+	built, err := a.BuildContractCtx(ctx, addressDetail.PublicKey, compiled.Code)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("build: %w", err)
+	}
+	signature, err := a.SignCtx(ctx, walletName, built.Hash)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("sign: %w", err)
+	}
+	tx, err := a.SendContractCtx(ctx, compiled.Code, built.UnsignedTx, signature, built.FromGroup)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("send: %w", err)
+	}
+	a.log.Debugf("Contract deployed with tx %s (%d -> %d), waiting for confirmation", tx.TransactionId, tx.FromGroup, tx.ToGroup)
+
+	_, err = a.WaitForTransactionConfirmed(ctx, tx.TransactionId, tx.FromGroup, tx.ToGroup)
+	return tx, err
 }
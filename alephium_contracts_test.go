@@ -0,0 +1,68 @@
+package alephium
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeployContract(t *testing.T) {
+
+	var calls []string
+	statusCalls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/wallets/contract-wallet/addresses":
+			_, _ = w.Write([]byte(`{"activeAddress":"1AujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n","addresses":[]}`))
+		case "/wallets/contract-wallet/addresses/1AujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n":
+			_, _ = w.Write([]byte(`{"address":"1AujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n","publicKey":"02abcd","group":2}`))
+		case "/contracts/compile":
+			var body CompileContractRequestBody
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, ContractTypeContract, body.Type)
+			assert.Equal(t, "1AujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n", body.Address)
+			_, _ = w.Write([]byte(`{"code":"0ecd"}`))
+		case "/contracts/build":
+			var body BuildContractRequestBody
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "02abcd", body.FromKey)
+			assert.Equal(t, "0ecd", body.Code)
+			_, _ = w.Write([]byte(`{"unsignedTx":"0ecd2065","hash":"798e","fromGroup":2,"toGroup":2}`))
+		case "/wallets/contract-wallet/sign":
+			var body SignRequest
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "798e", body.Data)
+			_, _ = w.Write([]byte(`{"signature":"5167"}`))
+		case "/contracts/send":
+			var body SendContractRequestBody
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, SendContractRequestBody{Code: "0ecd", Tx: "0ecd2065", Signature: "5167", FromGroup: 2}, body)
+			_, _ = w.Write([]byte(`{"txId":"503b","fromGroup":2,"toGroup":2}`))
+		case "/transactions/status":
+			statusCalls++
+			if statusCalls == 1 {
+				_, _ = w.Write([]byte(`{"type":"mem-pooled"}`))
+			} else {
+				_, _ = w.Write([]byte(`{"type":"confirmed","blockHash":"bdaf","chainConfirmations":1}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	alephiumClient, err := NewClient(ts.URL, WithPollInterval(time.Millisecond))
+	assert.Nil(t, err)
+
+	tx, err := alephiumClient.DeployContract(context.Background(), "contract-wallet", ContractTypeContract, "TxContract Foo() {}", "")
+	assert.Nil(t, err)
+	assert.Equal(t, Transaction{TransactionId: "503b", FromGroup: 2, ToGroup: 2}, tx)
+	assert.Equal(t, 2, statusCalls)
+	assert.Equal(t, "/contracts/send", calls[len(calls)-3])
+}
@@ -0,0 +1,136 @@
+package alephium
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/codec"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors matching the error schemas of the API, to be used with errors.Is
+var (
+	ErrBadRequest          = errors.New("bad request")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrNotFound            = errors.New("not found")
+	ErrInternalServerError = errors.New("internal server error")
+	ErrServiceUnavailable  = errors.New("service unavailable")
+	ErrWalletLocked        = errors.New("wallet is locked")
+	// ErrInvalidAddress is returned, before any call to the node, when an address can't be decoded
+	ErrInvalidAddress = errors.New("invalid address")
+	// ErrTransactionMismatch is returned when an unsigned transaction doesn't match what was requested
+	ErrTransactionMismatch = errors.New("transaction mismatch")
+)
+
+// APIError is returned when the node answers with a non-2xx status code.
+// The detail is empty if the node didn't send any.
+type APIError struct {
+	StatusCode int
+	ErrorDetail
+}
+
+func (e *APIError) Error() string {
+	if e.Detail != "" {
+		return e.Detail
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Unwrap returns the sentinel error matching the status code, if any
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusInternalServerError:
+		return ErrInternalServerError
+	case http.StatusServiceUnavailable:
+		return ErrServiceUnavailable
+	}
+	return nil
+}
+
+// Is reports ErrWalletLocked, which the node returns with various status codes
+func (e *APIError) Is(target error) bool {
+	if target == ErrWalletLocked {
+		return strings.Contains(strings.ToLower(e.Detail), "is locked")
+	}
+	return false
+}
+
+// As allows to retrieve the ErrorDetail, as returned before APIError was introduced
+func (e *APIError) As(target interface{}) bool {
+	if errorDetail, ok := target.(*ErrorDetail); ok {
+		*errorDetail = e.ErrorDetail
+		return true
+	}
+	return false
+}
+
+// TransferFailure is a destination of TransferMany which was not paid, or maybe paid if Uncertain
+type TransferFailure struct {
+	// Index is the index of the destination in the list given to TransferMany
+	Index       int
+	Destination TransferDestination
+	// Err is the error of the transaction which should have paid the destination
+	Err error
+	// Uncertain is true if the node may have submitted the transaction anyway, e.g. after a timeout or
+	// a server error. Retrying such a destination may pay it twice: its balance must be checked first.
+	// It is false if the node rejected the transaction, with a 4xx status, or if it was not sent.
+	Uncertain bool
+}
+
+// TransferManyError is returned by TransferMany when some of its transactions failed
+type TransferManyError struct {
+	Failures []TransferFailure
+}
+
+func (e *TransferManyError) Error() string {
+	uncertain := 0
+	for _, failure := range e.Failures {
+		if failure.Uncertain {
+			uncertain++
+		}
+	}
+	if uncertain > 0 {
+		return fmt.Sprintf("%d destinations not paid, %d of which maybe paid, first error: %v", len(e.Failures), uncertain, e.Failures[0].Err)
+	}
+	return fmt.Sprintf("%d destinations not paid, first error: %v", len(e.Failures), e.Failures[0].Err)
+}
+
+// Unwrap returns the error of the first failure, so that errors.Is(err, ErrWalletLocked) works for instance
+func (e *TransferManyError) Unwrap() error {
+	return e.Failures[0].Err
+}
+
+// validateAddress checks the address offline, see the address package
+func validateAddress(s string) error {
+	if err := address.Validate(s); err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidAddress, s, err)
+	}
+	return nil
+}
+
+// validateDestination checks the address, the tokens and the lock time of a destination offline
+func validateDestination(s string, tokens []Token, lockTime int64) error {
+	if err := validateAddress(s); err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if id, err := hex.DecodeString(token.Id); err != nil || len(id) != codec.HashLength {
+			return fmt.Errorf("invalid token id %q to %s", token.Id, s)
+		}
+		if token.Amount.Value == nil || token.Amount.Value.Sign() <= 0 {
+			return fmt.Errorf("invalid amount %s of token %s to %s", token.Amount, token.Id, s)
+		}
+	}
+	if lockTime < 0 {
+		return fmt.Errorf("invalid lock time %d to %s", lockTime, s)
+	}
+	return nil
+}
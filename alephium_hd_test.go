@@ -0,0 +1,35 @@
+package alephium_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/hd"
+	"testing"
+)
+
+// The genesis allocations of user-dev-standalone.conf are the miner addresses of the wallet
+// restored by the node from TestGenesisWalletMnemonics
+var testGenesisAddresses = []string{
+	"1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi",
+	"1Ambgi1jNRcBcdDUSfyrY2uQXdHpJs3zfc7Nmt6NcpBbL",
+	"1C5B3hMC9qu5s4JSmxtNbqEjKScoJRsbDtjwyFCcfELYw",
+	"18KzLirQvNQDh7J4Pu2QBxBwcerwJ9dELfh7QV7BNLfQa",
+}
+
+func TestHDGenesisWallet(t *testing.T) {
+
+	wallet, err := hd.NewWallet(alephium.TestGenesisWalletMnemonics, "", 4)
+	assert.Nil(t, err)
+
+	miners, err := wallet.MinerAccounts(0)
+	assert.Nil(t, err)
+	for group, miner := range miners {
+		assert.Equal(t, testGenesisAddresses[group], miner.Address)
+		assert.Equal(t, group, miner.Group)
+	}
+
+	// the first address of the wallet, i.e. its active address once restored
+	account, err := wallet.Account(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "1C5B3hMC9qu5s4JSmxtNbqEjKScoJRsbDtjwyFCcfELYw", account.Address)
+}
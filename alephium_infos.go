@@ -2,26 +2,31 @@ package alephium
 
 import (
 	"context"
-	"time"
 )
 
 // GetSelfCliqueInfos gets the infos about the current clique
 func (a *Client) GetSelfCliqueInfos() (SelfCliqueInfo, error) {
+	return a.GetSelfCliqueInfosCtx(context.Background())
+}
+
+// GetSelfCliqueInfosCtx is like GetSelfCliqueInfos, with a context
+func (a *Client) GetSelfCliqueInfosCtx(ctx context.Context) (SelfCliqueInfo, error) {
 	var selfCliqueInfos SelfCliqueInfo
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("infos/self-clique").
-		Receive(&selfCliqueInfos, &errorDetail)
-	return selfCliqueInfos, relevantError(err, errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("infos/self-clique"), &selfCliqueInfos)
+	return selfCliqueInfos, err
 }
 
 // GetInterCliquePeerInfos gets cliques about the other cliques connected to the current cllique
 func (a *Client) GetInterCliquePeerInfos() ([]InterCliquePeerInfo, error) {
+	return a.GetInterCliquePeerInfosCtx(context.Background())
+}
+
+// GetInterCliquePeerInfosCtx is like GetInterCliquePeerInfos, with a context
+func (a *Client) GetInterCliquePeerInfosCtx(ctx context.Context) ([]InterCliquePeerInfo, error) {
 	var interCliquePeerInfos []InterCliquePeerInfo
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("infos/inter-clique-peer-info").
-		Receive(&interCliquePeerInfos, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("infos/inter-clique-peer-info"), &interCliquePeerInfos)
 
-	return interCliquePeerInfos, relevantError(err, errorDetail)
+	return interCliquePeerInfos, err
 }
 
 // IsSyncedWithAtLeastOnePeer checks if the clique is connected with at least one clique
@@ -47,7 +52,7 @@ func (a *Client) WaitUntilSyncedWithAtLeastOnePeer(ctx context.Context) (bool, e
 
 		}
 		var err error
-		isSynced, err = a.IsSynced()
+		isSynced, err = a.IsSyncedCtx(ctx)
 		if err != nil {
 			return false, err
 		}
@@ -55,14 +60,21 @@ func (a *Client) WaitUntilSyncedWithAtLeastOnePeer(ctx context.Context) (bool, e
 			return true, nil
 		} else {
 			a.log.Debugf("Not sync'ed yet, sleeping %s", a.sleepTime)
-			time.Sleep(a.sleepTime)
+			if err := sleep(ctx, a.sleepTime); err != nil {
+				return false, err
+			}
 		}
 	}
 }
 
 // IsSynced checks if the cilque is synced
 func (a *Client) IsSynced() (bool, error) {
-	peers, err := a.GetInterCliquePeerInfos()
+	return a.IsSyncedCtx(context.Background())
+}
+
+// IsSyncedCtx is like IsSynced, with a context
+func (a *Client) IsSyncedCtx(ctx context.Context) (bool, error) {
+	peers, err := a.GetInterCliquePeerInfosCtx(ctx)
 	if err != nil {
 		return false, err
 	}
@@ -72,20 +84,26 @@ func (a *Client) IsSynced() (bool, error) {
 
 // GetDiscoveredNeighbors gets the discovered neighbors
 func (a *Client) GetDiscoveredNeighbors() ([]DiscoveredNeighbor, error) {
+	return a.GetDiscoveredNeighborsCtx(context.Background())
+}
+
+// GetDiscoveredNeighborsCtx is like GetDiscoveredNeighbors, with a context
+func (a *Client) GetDiscoveredNeighborsCtx(ctx context.Context) ([]DiscoveredNeighbor, error) {
 	var neighbors []DiscoveredNeighbor
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("infos/discovered-neighbors").
-		Receive(&neighbors, &errorDetail)
-	return neighbors, relevantError(err, errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("infos/discovered-neighbors"), &neighbors)
+	return neighbors, err
 }
 
 // GetMisbehaviors gets the misbehaving neighbors
 func (a *Client) GetMisbehaviors() ([]Misbehavior, error) {
+	return a.GetMisbehaviorsCtx(context.Background())
+}
+
+// GetMisbehaviorsCtx is like GetMisbehaviors, with a context
+func (a *Client) GetMisbehaviorsCtx(ctx context.Context) ([]Misbehavior, error) {
 	var misbehaviors []Misbehavior
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("infos/misbehaviors").
-		Receive(&misbehaviors, &errorDetail)
-	return misbehaviors, relevantError(err, errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("infos/misbehaviors"), &misbehaviors)
+	return misbehaviors, err
 }
 
 type MisbehaviorsBodyParams struct {
@@ -95,31 +113,47 @@ type MisbehaviorsBodyParams struct {
 
 // UnbanMisbehaviors unbans misbehaving neighbors
 func (a *Client) UnbanMisbehaviors(peers []string) (bool, error) {
-	return a.Misbehaviors("unban", peers)
+	return a.UnbanMisbehaviorsCtx(context.Background(), peers)
+}
+
+// UnbanMisbehaviorsCtx is like UnbanMisbehaviors, with a context
+func (a *Client) UnbanMisbehaviorsCtx(ctx context.Context, peers []string) (bool, error) {
+	return a.MisbehaviorsCtx(ctx, "unban", peers)
 }
 
 // BanMisbehaviors bans misbehaving neighbors
 func (a *Client) BanMisbehaviors(peers []string) (bool, error) {
-	return a.Misbehaviors("ban", peers)
+	return a.BanMisbehaviorsCtx(context.Background(), peers)
+}
+
+// BanMisbehaviorsCtx is like BanMisbehaviors, with a context
+func (a *Client) BanMisbehaviorsCtx(ctx context.Context, peers []string) (bool, error) {
+	return a.MisbehaviorsCtx(ctx, "ban", peers)
 }
 
 // Misbehaviors calls thee  misbehaviors endpoint
 func (a *Client) Misbehaviors(ptype string, peers []string) (bool, error) {
-	var errorDetail ErrorDetail
+	return a.MisbehaviorsCtx(context.Background(), ptype, peers)
+}
+
+// MisbehaviorsCtx is like Misbehaviors, with a context
+func (a *Client) MisbehaviorsCtx(ctx context.Context, ptype string, peers []string) (bool, error) {
 	params := MisbehaviorsBodyParams{
 		Type:  ptype,
 		Peers: peers,
 	}
-	_, err := a.slingClient.New().Post("infos/misbehaviors").
-		BodyJSON(params).Receive(nil, &errorDetail)
-	return true, relevantError(err, errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Post("infos/misbehaviors").BodyJSON(params), nil)
+	return true, err
 }
 
 // GetNodeInfos get the info of the node
 func (a *Client) GetNodeInfos() (NodeInfo, error) {
+	return a.GetNodeInfosCtx(context.Background())
+}
+
+// GetNodeInfosCtx is like GetNodeInfos, with a context
+func (a *Client) GetNodeInfosCtx(ctx context.Context) (NodeInfo, error) {
 	var nodeInfo NodeInfo
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("infos/node").
-		Receive(&nodeInfo, &errorDetail)
-	return nodeInfo, relevantError(err, errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("infos/node"), &nodeInfo)
+	return nodeInfo, err
 }
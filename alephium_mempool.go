@@ -0,0 +1,150 @@
+package alephium
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MempoolEventKind is the kind of a MempoolEvent
+type MempoolEventKind int
+
+const (
+	// MempoolAdded is fired when a transaction enters the mempool
+	MempoolAdded MempoolEventKind = iota + 1
+	// MempoolRemoved is fired when a transaction leaves the mempool, confirmed or dropped
+	MempoolRemoved
+)
+
+func (k MempoolEventKind) String() string {
+	switch k {
+	case MempoolAdded:
+		return "added"
+	case MempoolRemoved:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// MempoolEvent is a change of the mempool seen by a MempoolMonitor
+type MempoolEvent struct {
+	Kind        MempoolEventKind
+	FromGroup   int
+	ToGroup     int
+	Transaction TransactionTemplate
+}
+
+// MempoolMonitorConfig configures a MempoolMonitor
+type MempoolMonitorConfig struct {
+	// PollInterval is the interval between two snapshots, the poll interval of the client if 0
+	PollInterval time.Duration
+	// OnEvent, if set, is called with every event, from the polling loop
+	OnEvent func(MempoolEvent)
+	// Events, if set, receives every event. The polling loop blocks until they are received.
+	Events chan<- MempoolEvent
+}
+
+// MempoolMonitor diffs successive snapshots of the mempool, from GetUnconfirmedTransactions,
+// and fires an event for every transaction added or removed. The transactions of the first
+// snapshot are all added.
+type MempoolMonitor struct {
+	client *Client
+	config MempoolMonitorConfig
+
+	mu    sync.Mutex
+	known map[string]MempoolEvent
+}
+
+// NewMempoolMonitor creates a MempoolMonitor polling the node of the client
+func (a *Client) NewMempoolMonitor(config MempoolMonitorConfig) *MempoolMonitor {
+	if config.PollInterval <= 0 {
+		config.PollInterval = a.sleepTime
+	}
+	return &MempoolMonitor{
+		client: a,
+		config: config,
+		known:  make(map[string]MempoolEvent),
+	}
+}
+
+// Run takes a snapshot of the mempool every poll interval until the context is done, returning its error.
+// Failing snapshots are logged and taken again at the next interval.
+func (m *MempoolMonitor) Run(ctx context.Context) error {
+	for {
+		if err := m.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			m.client.log.Warnf("Failed to get the unconfirmed transactions: %v", err)
+		}
+		if err := sleep(ctx, m.config.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// Poll takes a snapshot of the mempool and fires the events of the differences with the previous one
+func (m *MempoolMonitor) Poll(ctx context.Context) error {
+	snapshot, err := m.client.GetUnconfirmedTransactionsCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	current := make(map[string]MempoolEvent)
+	var events []MempoolEvent
+	for _, chain := range snapshot {
+		for _, tx := range chain.UnconfirmedTransactions {
+			event := MempoolEvent{FromGroup: chain.FromGroup, ToGroup: chain.ToGroup, Transaction: tx}
+			current[tx.Unsigned.TxId] = event
+			if _, ok := m.known[tx.Unsigned.TxId]; !ok {
+				event.Kind = MempoolAdded
+				events = append(events, event)
+			}
+		}
+	}
+	removed := make([]string, 0)
+	for id := range m.known {
+		if _, ok := current[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+	for _, id := range removed {
+		event := m.known[id]
+		event.Kind = MempoolRemoved
+		events = append(events, event)
+	}
+	m.known = current
+	m.mu.Unlock()
+
+	for _, event := range events {
+		m.client.log.Debugf("Tx %s %s", event.Transaction.Unsigned.TxId, event.Kind)
+		if m.config.OnEvent != nil {
+			m.config.OnEvent(event)
+		}
+		if m.config.Events != nil {
+			select {
+			case m.config.Events <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+// Transactions returns the transactions of the last snapshot
+func (m *MempoolMonitor) Transactions() []MempoolEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	transactions := make([]MempoolEvent, 0, len(m.known))
+	for _, event := range m.known {
+		transactions = append(transactions, event)
+	}
+	sort.Slice(transactions, func(i, j int) bool {
+		return transactions[i].Transaction.Unsigned.TxId < transactions[j].Transaction.Unsigned.TxId
+	})
+	return transactions
+}
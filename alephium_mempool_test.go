@@ -0,0 +1,72 @@
+package alephium
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/touilleio/alephium-go-client/address"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testUnlockScript = "000279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+
+func unconfirmedTx(txId string) string {
+	return fmt.Sprintf(`{"unsigned":{"txId":"%s","version":0,"networkId":1,"gasAmount":20000,"gasPrice":"100000000000",
+		"inputs":[{"outputRef":{"hint":1,"key":"%s"},"unlockScript":"%s"}],
+		"fixedOutputs":[{"hint":2,"key":"%s","amount":"1000000000000000000","address":"1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi",
+		"tokens":[],"lockTime":0,"additionalData":""}]},"inputSignatures":["00"],"contractSignatures":[]}`,
+		txId, strings.Repeat("11", 32), testUnlockScript, strings.Repeat("22", 32))
+}
+
+func TestMempoolMonitor(t *testing.T) {
+
+	var mu sync.Mutex
+	var chains []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("[" + strings.Join(chains, ",") + "]"))
+	}))
+	defer ts.Close()
+	setMempool := func(c ...string) {
+		mu.Lock()
+		defer mu.Unlock()
+		chains = c
+	}
+
+	alephiumClient, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	setMempool(`{"fromGroup":0,"toGroup":1,"unconfirmedTransactions":[` + unconfirmedTx("a") + `,` + unconfirmedTx("b") + `]}`)
+	unconfirmed, err := alephiumClient.GetUnconfirmedTransactions()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(unconfirmed))
+	assert.Equal(t, 1, unconfirmed[0].ToGroup)
+	tx := unconfirmed[0].UnconfirmedTransactions[0].Unsigned
+	assert.Equal(t, "a", tx.TxId)
+	assert.Equal(t, "100000000000", tx.GasPrice.String())
+	assert.Equal(t, "1ALPH", tx.FixedOutputs[0].Amount.PrettyString())
+	from, err := tx.Inputs[0].FromAddress()
+	assert.Nil(t, err)
+	publicKey, _ := hex.DecodeString(testUnlockScript[2:])
+	assert.Equal(t, address.NewP2PKH(publicKey).String(), from)
+
+	var events []string
+	monitor := alephiumClient.NewMempoolMonitor(MempoolMonitorConfig{OnEvent: func(event MempoolEvent) {
+		events = append(events, fmt.Sprintf("%s %s %d->%d", event.Kind, event.Transaction.Unsigned.TxId, event.FromGroup, event.ToGroup))
+	}})
+	ctx := context.Background()
+	assert.Nil(t, monitor.Poll(ctx))
+	assert.Nil(t, monitor.Poll(ctx))
+	setMempool(`{"fromGroup":0,"toGroup":1,"unconfirmedTransactions":[`+unconfirmedTx("b")+`]}`,
+		`{"fromGroup":2,"toGroup":2,"unconfirmedTransactions":[`+unconfirmedTx("c")+`]}`)
+	assert.Nil(t, monitor.Poll(ctx))
+	assert.Equal(t, []string{"added a 0->1", "added b 0->1", "added c 2->2", "removed a 0->1"}, events)
+	assert.Equal(t, 2, len(monitor.Transactions()))
+}
@@ -1,30 +1,42 @@
 package alephium
 
+import (
+	"context"
+)
+
 // StartMining starts the built-in CPU miner. Mostly for tests
 func (a *Client) StartMining() (bool, error) {
-	return a.miningAction("start-mining")
+	return a.StartMiningCtx(context.Background())
+}
+
+// StartMiningCtx is like StartMining, with a context
+func (a *Client) StartMiningCtx(ctx context.Context) (bool, error) {
+	return a.miningAction(ctx, "start-mining")
 }
 
 // StopMining stops the built-in CPU miner
 func (a *Client) StopMining() (bool, error) {
-	return a.miningAction("stop-mining")
+	return a.StopMiningCtx(context.Background())
+}
+
+// StopMiningCtx is like StopMining, with a context
+func (a *Client) StopMiningCtx(ctx context.Context) (bool, error) {
+	return a.miningAction(ctx, "stop-mining")
 }
 
 type MiningActionRequestParams struct {
 	Action string `url:"action"`
 }
 
-func (a *Client) miningAction(action string) (bool, error) {
+func (a *Client) miningAction(ctx context.Context, action string) (bool, error) {
 
-	var errorDetail ErrorDetail
 	var actionOk bool
 	params := MiningActionRequestParams{
 		Action: action,
 	}
-	_, err := a.slingClient.New().Post("miners").
-		QueryStruct(params).Receive(&actionOk, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Post("miners").QueryStruct(params), &actionOk)
 
-	return actionOk, relevantError(err, errorDetail)
+	return actionOk, err
 }
 
 type UpdateMinersAddressesBodyParams struct {
@@ -33,24 +45,56 @@ type UpdateMinersAddressesBodyParams struct {
 
 // UpdateMinersAddresses updates the miner addresses
 func (a *Client) UpdateMinersAddresses(addresses []string) error {
+	return a.UpdateMinersAddressesCtx(context.Background(), addresses)
+}
+
+// UpdateMinersAddressesCtx is like UpdateMinersAddresses, with a context
+func (a *Client) UpdateMinersAddressesCtx(ctx context.Context, addresses []string) error {
 
-	var errorDetail ErrorDetail
 	params := UpdateMinersAddressesBodyParams{
 		Addresses: addresses,
 	}
-	_, err := a.slingClient.New().Put("miners/addresses").
-		BodyJSON(params).Receive(nil, &errorDetail)
-
-	return relevantError(err, errorDetail)
+	return a.receive(ctx, a.slingClient.New().Put("miners/addresses").BodyJSON(params), nil)
 }
 
 // GetMinersAddresses gets the current miner's addresses
 func (a *Client) GetMinersAddresses() (MinersAddresses, error) {
+	return a.GetMinersAddressesCtx(context.Background())
+}
+
+// GetMinersAddressesCtx is like GetMinersAddresses, with a context
+func (a *Client) GetMinersAddressesCtx(ctx context.Context) (MinersAddresses, error) {
 
 	var minersAddresses MinersAddresses
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("miners/addresses").
-		Receive(&minersAddresses, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("miners/addresses"), &minersAddresses)
+
+	return minersAddresses, err
+}
+
+// GetBlockCandidate gets a block template to be mined on the chain fromGroup -> toGroup
+func (a *Client) GetBlockCandidate(fromGroup int, toGroup int) (BlockCandidate, error) {
+	return a.GetBlockCandidateCtx(context.Background(), fromGroup, toGroup)
+}
+
+// GetBlockCandidateCtx is like GetBlockCandidate, with a context
+func (a *Client) GetBlockCandidateCtx(ctx context.Context, fromGroup int, toGroup int) (BlockCandidate, error) {
+
+	var blockCandidate BlockCandidate
+	params := ChainRequestParams{
+		FromGroup: fromGroup,
+		ToGroup:   toGroup,
+	}
+	err := a.receive(ctx, a.slingClient.New().Get("miners/block-candidate").QueryStruct(params), &blockCandidate)
+
+	return blockCandidate, err
+}
+
+// SubmitBlockSolution submits a mined block to the node
+func (a *Client) SubmitBlockSolution(solution BlockSolution) error {
+	return a.SubmitBlockSolutionCtx(context.Background(), solution)
+}
 
-	return minersAddresses, relevantError(err, errorDetail)
+// SubmitBlockSolutionCtx is like SubmitBlockSolution, with a context
+func (a *Client) SubmitBlockSolutionCtx(ctx context.Context, solution BlockSolution) error {
+	return a.receive(ctx, a.slingClient.New().Post("miners/new-block").BodyJSON(solution), nil)
 }
@@ -0,0 +1,65 @@
+package alephium
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBlockCandidateAndSolution(t *testing.T) {
+
+	var solution map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/miners/block-candidate":
+			assert.Equal(t, "1", r.URL.Query().Get("fromGroup"))
+			assert.Equal(t, "2", r.URL.Query().Get("toGroup"))
+			_, _ = w.Write([]byte(`{"deps":["bdaf"],"depStateHash":"798e","target":"1a400000","blockTs":1611041396892,"txsHash":"798f","transactions":["0ecd"]}`))
+		case "/miners/new-block":
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&solution))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	alephiumClient, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	candidate, err := alephiumClient.GetBlockCandidate(1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, BlockCandidate{
+		Deps:         []string{"bdaf"},
+		DepStateHash: "798e",
+		Target:       "1a400000",
+		BlockTs:      1611041396892,
+		TxsHash:      "798f",
+		Transactions: []string{"0ecd"},
+	}, candidate)
+
+	err = alephiumClient.SubmitBlockSolution(BlockSolution{
+		BlockDeps:    candidate.Deps,
+		DepStateHash: candidate.DepStateHash,
+		Timestamp:    candidate.BlockTs,
+		FromGroup:    1,
+		ToGroup:      2,
+		MiningCount:  U256{Value: big.NewInt(1234)},
+		Target:       candidate.Target,
+		Nonce:        U256{Value: big.NewInt(42)},
+		TxsHash:      candidate.TxsHash,
+		Transactions: candidate.Transactions,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "42", solution["nonce"])
+	assert.Equal(t, "1234", solution["miningCount"])
+	assert.Equal(t, float64(2), solution["toGroup"])
+
+	var nonce U256
+	assert.Nil(t, json.Unmarshal([]byte(`42`), &nonce))
+	assert.Equal(t, "42", nonce.String())
+	assert.NotNil(t, json.Unmarshal([]byte(`"-1"`), &nonce))
+}
@@ -1,8 +1,13 @@
 package alephium
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/codec"
 	"math/big"
+	"strings"
+	"time"
 )
 
 type WalletInfo struct {
@@ -44,6 +49,7 @@ func (b AddressBalance) GetBalance() (*big.Int, bool) {
 	return b.Balance.Amount, true
 }
 
+// Deprecated: use errors.Is(err, ErrWalletLocked) instead.
 var WalletLockError = ErrorDetail{
 	Detail: "WalletInfo is locked",
 }
@@ -118,6 +124,7 @@ type Address struct {
 	Address string `json:"address"`
 }
 
+// TransactionStatus is the status of a transaction as returned by the node, see TxStatus for a typed version
 type TransactionStatus struct {
 	Type                   string `json:"type"`
 	BlockHash              string `json:"blockHash"`
@@ -127,6 +134,96 @@ type TransactionStatus struct {
 	ToGroupConfirmations   int    `json:"toGroupConfirmations"`
 }
 
+// TxStatus converts the status to its typed version, according to its type, whatever the node version
+// ("confirmed" or "Confirmed", "mem-pooled" or "MemPooled", "tx-not-found" or "TxNotFound")
+func (s TransactionStatus) TxStatus() (TxStatus, error) {
+	switch normalizeTxStatusType(s.Type) {
+	case "confirmed":
+		return Confirmed{
+			BlockHash:              s.BlockHash,
+			BlockIndex:             s.BlockIndex,
+			ChainConfirmations:     s.ChainConfirmations,
+			FromGroupConfirmations: s.FromGroupConfirmations,
+			ToGroupConfirmations:   s.ToGroupConfirmations,
+		}, nil
+	case "mempooled":
+		return MemPooled{}, nil
+	case "txnotfound", "notfound":
+		return TxNotFound{}, nil
+	}
+	return nil, fmt.Errorf("unknown transaction status %q", s.Type)
+}
+
+// normalizeTxStatusType lower cases the type of a status and removes its dashes
+func normalizeTxStatusType(t string) string {
+	return strings.ReplaceAll(strings.ToLower(t), "-", "")
+}
+
+// TxStatus is the status of a transaction, one of Confirmed, MemPooled or TxNotFound
+type TxStatus interface {
+	// IsFinal is true if the transaction is confirmed with at least minConfirmations chain,
+	// from group and to group confirmations
+	IsFinal(minConfirmations int) bool
+	String() string
+	isTxStatus()
+}
+
+// Confirmed is the status of a transaction included in a block
+type Confirmed struct {
+	BlockHash              string
+	BlockIndex             int
+	ChainConfirmations     int
+	FromGroupConfirmations int
+	ToGroupConfirmations   int
+}
+
+func (c Confirmed) IsFinal(minConfirmations int) bool {
+	return c.ChainConfirmations >= minConfirmations && c.FromGroupConfirmations >= minConfirmations &&
+		c.ToGroupConfirmations >= minConfirmations
+}
+
+func (c Confirmed) String() string {
+	return fmt.Sprintf("confirmed in block %s (%d chain, %d from group, %d to group confirmations)",
+		c.BlockHash, c.ChainConfirmations, c.FromGroupConfirmations, c.ToGroupConfirmations)
+}
+
+func (Confirmed) isTxStatus() {}
+
+// MemPooled is the status of a transaction waiting in the mempool
+type MemPooled struct{}
+
+func (MemPooled) IsFinal(int) bool {
+	return false
+}
+
+func (MemPooled) String() string {
+	return "mem-pooled"
+}
+
+func (MemPooled) isTxStatus() {}
+
+// TxNotFound is the status of a transaction unknown to the node
+type TxNotFound struct{}
+
+func (TxNotFound) IsFinal(int) bool {
+	return false
+}
+
+func (TxNotFound) String() string {
+	return "not found"
+}
+
+func (TxNotFound) isTxStatus() {}
+
+// UnmarshalTxStatus decodes a TxStatus from its JSON representation, according to its type
+func UnmarshalTxStatus(b []byte) (TxStatus, error) {
+	var status TransactionStatus
+	if err := json.Unmarshal(b, &status); err != nil {
+		return nil, err
+	}
+	return status.TxStatus()
+}
+
 type AddressUtxoBalance struct {
 	Balance           ALPH   `json:"balance"`
 	BalanceHint       string `json:"balanceHint"`
@@ -154,3 +251,181 @@ type MisbehaviorStatus struct {
 	Type  string `json:"type"`
 	Value int    `json:"value"`
 }
+
+type FetchResponse struct {
+	Blocks []BlockEntry `json:"blocks"`
+}
+
+type BlockEntry struct {
+	Hash         string   `json:"hash"`
+	Timestamp    int64    `json:"timestamp"`
+	ChainFrom    int      `json:"chainFrom"`
+	ChainTo      int      `json:"chainTo"`
+	Height       int      `json:"height"`
+	Deps         []string `json:"deps"`
+	Transactions []Tx     `json:"transactions"`
+}
+
+type Tx struct {
+	Id      string   `json:"id"`
+	Inputs  []Input  `json:"inputs"`
+	Outputs []Output `json:"outputs"`
+}
+
+type Input struct {
+	OutputRef    TxOutputRef `json:"outputRef"`
+	UnlockScript string      `json:"unlockScript"`
+}
+
+// TxOutputRef references the output spent by an Input
+type TxOutputRef struct {
+	ScriptHint int    `json:"scriptHint"`
+	Key        string `json:"key"`
+}
+
+type Output struct {
+	Amount   ALPH    `json:"amount"`
+	Address  string  `json:"address"`
+	Tokens   []Token `json:"tokens"`
+	LockTime int64   `json:"lockTime"`
+}
+
+type HashesAtHeight struct {
+	Headers []string `json:"headers"`
+}
+
+type ChainInfo struct {
+	CurrentHeight int `json:"currentHeight"`
+}
+
+type BlockCandidate struct {
+	Deps         []string `json:"deps"`
+	DepStateHash string   `json:"depStateHash"`
+	Target       string   `json:"target"`
+	BlockTs      int64    `json:"blockTs"`
+	TxsHash      string   `json:"txsHash"`
+	Transactions []string `json:"transactions"`
+}
+
+type BlockSolution struct {
+	BlockDeps    []string `json:"blockDeps"`
+	DepStateHash string   `json:"depStateHash"`
+	Timestamp    int64    `json:"timestamp"`
+	FromGroup    int      `json:"fromGroup"`
+	ToGroup      int      `json:"toGroup"`
+	MiningCount  U256     `json:"miningCount"`
+	Target       string   `json:"target"`
+	Nonce        U256     `json:"nonce"`
+	TxsHash      string   `json:"txsHash"`
+	Transactions []string `json:"transactions"`
+}
+
+// UnconfirmedTransactions are the transactions of the mempool of the chain from a group to a group
+type UnconfirmedTransactions struct {
+	FromGroup               int                   `json:"fromGroup"`
+	ToGroup                 int                   `json:"toGroup"`
+	UnconfirmedTransactions []TransactionTemplate `json:"unconfirmedTransactions"`
+}
+
+// TransactionTemplate is a signed transaction not confirmed yet
+type TransactionTemplate struct {
+	Unsigned           UnsignedTx `json:"unsigned"`
+	InputSignatures    []string   `json:"inputSignatures"`
+	ContractSignatures []string   `json:"contractSignatures"`
+}
+
+// UnsignedTx is a decoded unsigned transaction, as returned by the node
+type UnsignedTx struct {
+	TxId         string             `json:"txId"`
+	Version      int                `json:"version"`
+	NetworkId    int                `json:"networkId"`
+	ScriptOpt    string             `json:"scriptOpt,omitempty"`
+	GasAmount    int                `json:"gasAmount"`
+	GasPrice     U256               `json:"gasPrice"`
+	Inputs       []AssetInput       `json:"inputs"`
+	FixedOutputs []FixedAssetOutput `json:"fixedOutputs"`
+}
+
+// AssetInput spends the output it references
+type AssetInput struct {
+	OutputRef    OutputRef `json:"outputRef"`
+	UnlockScript string    `json:"unlockScript"`
+}
+
+// FromAddress returns the P2PKH address of the output spent, derived from the public key of the unlock script.
+// The address of P2MPKH outputs can't be derived from the public keys of the signers only.
+func (i AssetInput) FromAddress() (string, error) {
+	script, err := codec.DecodeUnlockScriptHex(i.UnlockScript)
+	if err != nil {
+		return "", fmt.Errorf("invalid unlock script %s: %w", i.UnlockScript, err)
+	}
+	if script.Type != codec.UnlockP2PKH {
+		return "", fmt.Errorf("the address of unlock script %s can't be derived, not P2PKH", i.UnlockScript)
+	}
+	return address.NewP2PKH(script.PublicKeys[0].PublicKey).String(), nil
+}
+
+// FixedAssetOutput is an output of a transaction
+type FixedAssetOutput struct {
+	Hint    int     `json:"hint"`
+	Key     string  `json:"key"`
+	Amount  ALPH    `json:"amount"`
+	Address string  `json:"address"`
+	Tokens  []Token `json:"tokens"`
+	// LockTime is the timestamp, in milliseconds, until which the output can't be spent
+	LockTime       int64  `json:"lockTime"`
+	AdditionalData string `json:"additionalData"`
+}
+
+// Utxo is an unspent output of an address
+type Utxo struct {
+	Ref    OutputRef `json:"ref"`
+	Amount ALPH      `json:"amount"`
+	Tokens []Token   `json:"tokens"`
+	// LockTime is the timestamp, in milliseconds, until which the output can't be spent
+	LockTime       int64  `json:"lockTime"`
+	AdditionalData string `json:"additionalData"`
+}
+
+// Spendable is true if the output is not locked anymore at the given time
+func (u Utxo) Spendable(now time.Time) bool {
+	return u.LockTime <= now.UnixNano()/int64(time.Millisecond)
+}
+
+// OutputRef references an output, by the hint of its lockup script and its key
+type OutputRef struct {
+	Hint int    `json:"hint"`
+	Key  string `json:"key"`
+}
+
+// Token is an amount of the token of the given id
+type Token struct {
+	Id     string `json:"id"`
+	Amount U256   `json:"amount"`
+}
+
+// U256 is an unsigned 256 bits integer, serialized as a JSON string like ALPH
+type U256 struct {
+	Value *big.Int
+}
+
+func (u U256) String() string {
+	if u.Value == nil {
+		return "0"
+	}
+	return u.Value.String()
+}
+
+func (u U256) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + u.String() + `"`), nil
+}
+
+func (u *U256) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	value, ok := new(big.Int).SetString(s, 10)
+	if !ok || value.Sign() < 0 {
+		return fmt.Errorf("invalid U256 %s", s)
+	}
+	u.Value = value
+	return nil
+}
@@ -0,0 +1,293 @@
+package alephium
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/codec"
+	"strings"
+)
+
+type MultisigAddressRequestBody struct {
+	Keys      []string `json:"keys"`
+	MRequired int      `json:"mrequired"`
+}
+
+type MultisigAddress struct {
+	Address string `json:"address"`
+}
+
+// CreateMultisigAddress creates the m-of-n (P2MPKH) address of the given public keys
+func (a *Client) CreateMultisigAddress(publicKeys []string, mRequired int) (MultisigAddress, error) {
+	return a.CreateMultisigAddressCtx(context.Background(), publicKeys, mRequired)
+}
+
+// CreateMultisigAddressCtx is like CreateMultisigAddress, with a context
+func (a *Client) CreateMultisigAddressCtx(ctx context.Context, publicKeys []string, mRequired int) (MultisigAddress, error) {
+
+	var multisigAddress MultisigAddress
+
+	if err := validatePublicKeys(publicKeys); err != nil {
+		return multisigAddress, err
+	}
+	if mRequired <= 0 || mRequired > len(publicKeys) {
+		return multisigAddress, fmt.Errorf("invalid multisig, %d signatures required for %d public keys", mRequired, len(publicKeys))
+	}
+	body := MultisigAddressRequestBody{
+		Keys:      publicKeys,
+		MRequired: mRequired,
+	}
+	err := a.receive(ctx, a.slingClient.New().Post("multisig/address").BodyJSON(body), &multisigAddress)
+
+	return multisigAddress, err
+}
+
+type BuildMultisigRequestBody struct {
+	FromAddress    string                   `json:"fromAddress"`
+	FromPublicKeys []string                 `json:"fromPublicKeys"`
+	Destinations   []TransactionDestination `json:"destinations"`
+}
+
+// BuildMultisigTransaction builds an unsigned transaction spending from a multisig address, to be signed
+// by the owners of the given public keys, as many as the signatures required by the address. The keys
+// must be keys of the address, in the order they were given to CreateMultisigAddress.
+func (a *Client) BuildMultisigTransaction(fromAddress string, signingPublicKeys []string,
+	destinations []TransactionDestination) (PartiallySignedTransaction, error) {
+	return a.BuildMultisigTransactionCtx(context.Background(), fromAddress, signingPublicKeys, destinations)
+}
+
+// BuildMultisigTransactionCtx is like BuildMultisigTransaction, with a context
+func (a *Client) BuildMultisigTransactionCtx(ctx context.Context, fromAddress string, signingPublicKeys []string,
+	destinations []TransactionDestination) (PartiallySignedTransaction, error) {
+
+	from, err := address.Decode(fromAddress)
+	if err != nil {
+		return PartiallySignedTransaction{}, fmt.Errorf("%w %q: %v", ErrInvalidAddress, fromAddress, err)
+	}
+	if from.Type() != address.P2MPKH {
+		return PartiallySignedTransaction{}, fmt.Errorf("%w %q: %s address, not a multisig one", ErrInvalidAddress, fromAddress, from.Type())
+	}
+	if err := validatePublicKeys(signingPublicKeys); err != nil {
+		return PartiallySignedTransaction{}, err
+	}
+	if len(signingPublicKeys) != from.RequiredSignatures() {
+		return PartiallySignedTransaction{}, fmt.Errorf("%d signatures required by %s, got %d public keys",
+			from.RequiredSignatures(), fromAddress, len(signingPublicKeys))
+	}
+	signingPublicKeys, err = multisigSigners(from, signingPublicKeys)
+	if err != nil {
+		return PartiallySignedTransaction{}, err
+	}
+	for _, destination := range destinations {
+		if err := destination.Validate(); err != nil {
+			return PartiallySignedTransaction{}, err
+		}
+	}
+
+	body := BuildMultisigRequestBody{
+		FromAddress:    fromAddress,
+		FromPublicKeys: signingPublicKeys,
+		Destinations:   destinations,
+	}
+	var unsignedTx UnsignedTransaction
+	err = a.receive(ctx, a.slingClient.New().Post("multisig/build").BodyJSON(body), &unsignedTx)
+	if err != nil {
+		return PartiallySignedTransaction{}, err
+	}
+
+	return NewPartiallySignedTransaction(fromAddress, signingPublicKeys, unsignedTx), nil
+}
+
+// SignMultisigTransaction signs the transaction with a wallet of the node, whose active address
+// must be the one of the given public key, and adds the signature to the transaction
+func (a *Client) SignMultisigTransaction(walletName string, publicKey string, tx *PartiallySignedTransaction) error {
+	return a.SignMultisigTransactionCtx(context.Background(), walletName, publicKey, tx)
+}
+
+// SignMultisigTransactionCtx is like SignMultisigTransaction, with a context
+func (a *Client) SignMultisigTransactionCtx(ctx context.Context, walletName string, publicKey string, tx *PartiallySignedTransaction) error {
+	if err := tx.CheckTxId(); err != nil {
+		return err
+	}
+	signature, err := a.SignCtx(ctx, walletName, tx.TxId)
+	if err != nil {
+		return err
+	}
+	return tx.AddSignature(publicKey, signature)
+}
+
+type SubmitMultisigRequestBody struct {
+	UnsignedTx string   `json:"unsignedTx"`
+	Signatures []string `json:"signatures"`
+}
+
+// SubmitMultisigTransaction submits a transaction signed by all the required parties
+func (a *Client) SubmitMultisigTransaction(tx PartiallySignedTransaction) (Transaction, error) {
+	return a.SubmitMultisigTransactionCtx(context.Background(), tx)
+}
+
+// SubmitMultisigTransactionCtx is like SubmitMultisigTransaction, with a context
+func (a *Client) SubmitMultisigTransactionCtx(ctx context.Context, tx PartiallySignedTransaction) (Transaction, error) {
+
+	var transaction Transaction
+
+	signatures, err := tx.OrderedSignatures()
+	if err != nil {
+		return transaction, err
+	}
+	body := SubmitMultisigRequestBody{
+		UnsignedTx: tx.UnsignedTx,
+		Signatures: signatures,
+	}
+	err = a.receive(ctx, a.slingClient.New().Post("multisig/submit").BodyJSON(body), &transaction)
+
+	return transaction, err
+}
+
+// PartiallySignedTransaction is a multisig transaction collecting the signatures of its signers.
+// It is meant to be marshalled to JSON and passed from one signer to the next one.
+type PartiallySignedTransaction struct {
+	FromAddress string `json:"fromAddress"`
+	// PublicKeys are the public keys of the signers, in the order given to BuildMultisigTransaction
+	PublicKeys []string `json:"publicKeys"`
+	UnsignedTx string   `json:"unsignedTx"`
+	TxId       string   `json:"txId"`
+	FromGroup  int      `json:"fromGroup"`
+	ToGroup    int      `json:"toGroup"`
+	// Signatures are the signatures collected so far, by public key
+	Signatures map[string]string `json:"signatures"`
+}
+
+// NewPartiallySignedTransaction creates an envelope without any signature for the unsigned transaction
+func NewPartiallySignedTransaction(fromAddress string, signingPublicKeys []string, unsignedTx UnsignedTransaction) PartiallySignedTransaction {
+	publicKeys := make([]string, len(signingPublicKeys))
+	for i, publicKey := range signingPublicKeys {
+		publicKeys[i] = strings.ToLower(publicKey)
+	}
+	return PartiallySignedTransaction{
+		FromAddress: fromAddress,
+		PublicKeys:  publicKeys,
+		UnsignedTx:  unsignedTx.UnsignedTx,
+		TxId:        unsignedTx.TxId,
+		FromGroup:   unsignedTx.FromGroup,
+		ToGroup:     unsignedTx.ToGroup,
+		Signatures:  make(map[string]string),
+	}
+}
+
+// CheckTxId checks that TxId is the id of UnsignedTx, which must be done before signing it.
+// An error wrapping ErrTransactionMismatch is returned otherwise.
+func (p PartiallySignedTransaction) CheckTxId() error {
+	tx, err := codec.DecodeUnsignedTxHex(p.UnsignedTx)
+	if err != nil {
+		return fmt.Errorf("invalid unsigned tx: %w", err)
+	}
+	if tx.Id() != strings.ToLower(p.TxId) {
+		return fmt.Errorf("%w: tx id %s, computed %s", ErrTransactionMismatch, p.TxId, tx.Id())
+	}
+	return nil
+}
+
+// AddSignature adds the signature of the TxId by the owner of the public key, after checking it
+// against the id of UnsignedTx
+func (p *PartiallySignedTransaction) AddSignature(publicKey string, signature string) error {
+	publicKey = strings.ToLower(publicKey)
+	if !p.isSigner(publicKey) {
+		return fmt.Errorf("%s is not a signer of tx %s", publicKey, p.TxId)
+	}
+	if err := p.CheckTxId(); err != nil {
+		return err
+	}
+	ok, err := VerifySignature(publicKey, p.TxId, signature)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("invalid signature %s of tx %s by %s", signature, p.TxId, publicKey)
+	}
+	if p.Signatures == nil {
+		p.Signatures = make(map[string]string)
+	}
+	p.Signatures[publicKey] = signature
+	return nil
+}
+
+// Missing returns the public keys of the signers who didn't sign yet
+func (p PartiallySignedTransaction) Missing() []string {
+	missing := make([]string, 0)
+	for _, publicKey := range p.PublicKeys {
+		if _, ok := p.Signatures[publicKey]; !ok {
+			missing = append(missing, publicKey)
+		}
+	}
+	return missing
+}
+
+// Complete is true once all the signers signed
+func (p PartiallySignedTransaction) Complete() bool {
+	return len(p.Missing()) == 0
+}
+
+// OrderedSignatures returns the signatures in the order of the public keys, as expected by the node
+func (p PartiallySignedTransaction) OrderedSignatures() ([]string, error) {
+	if missing := p.Missing(); len(missing) > 0 {
+		return nil, fmt.Errorf("tx %s is missing %d signatures, from %v", p.TxId, len(missing), missing)
+	}
+	signatures := make([]string, len(p.PublicKeys))
+	for i, publicKey := range p.PublicKeys {
+		signatures[i] = p.Signatures[publicKey]
+	}
+	return signatures, nil
+}
+
+// isSigner is true if the lower case public key is one of the signers, whatever the case of their keys
+func (p PartiallySignedTransaction) isSigner(publicKey string) bool {
+	for _, k := range p.PublicKeys {
+		if strings.ToLower(k) == publicKey {
+			return true
+		}
+	}
+	return false
+}
+
+// multisigSigners checks offline that the signing public keys are keys of the P2MPKH address, in the
+// order of the address, and returns them in lower case
+func multisigSigners(from address.Address, signingPublicKeys []string) ([]string, error) {
+	hashes := from.Hashes()
+	signers := make([]string, len(signingPublicKeys))
+	previous := -1
+	for i, publicKey := range signingPublicKeys {
+		key, _ := hex.DecodeString(publicKey)
+		hash := address.NewP2PKH(key).Hashes()[0]
+		index := -1
+		for j, h := range hashes {
+			if bytes.Equal(h, hash) {
+				index = j
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("%s is not a public key of the multisig address %s", publicKey, from)
+		}
+		if index <= previous {
+			return nil, fmt.Errorf("the public keys must be in the order of the multisig address %s, %s is not", from, publicKey)
+		}
+		previous = index
+		signers[i] = strings.ToLower(publicKey)
+	}
+	return signers, nil
+}
+
+func validatePublicKeys(publicKeys []string) error {
+	if len(publicKeys) == 0 {
+		return fmt.Errorf("at least one public key is required")
+	}
+	for _, publicKey := range publicKeys {
+		if b, err := hex.DecodeString(publicKey); err != nil || len(b) != 33 {
+			return fmt.Errorf("invalid public key %s, expected 33 hex encoded bytes", publicKey)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package alephium_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/codec"
+	"github.com/touilleio/alephium-go-client/signer"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMultisig(t *testing.T) {
+
+	signers := make([]*signer.Signer, 3)
+	publicKeys := make([]string, 3)
+	keys := make([][]byte, 3)
+	for i := range signers {
+		signers[i], _ = signer.Generate()
+		publicKeys[i] = signers[i].PublicKey()
+		keys[i], _ = hex.DecodeString(publicKeys[i])
+	}
+	multisig, err := address.NewP2MPKH(keys, 2)
+	assert.Nil(t, err)
+	unsignedTx := codec.UnsignedTx{
+		NetworkId: 1,
+		GasAmount: codec.MinimalGas,
+		GasPrice:  codec.DefaultGasPrice,
+		Inputs: []codec.Input{{
+			Hint: 1,
+			Key:  make([]byte, codec.HashLength),
+			UnlockScript: codec.UnlockScript{Type: codec.UnlockP2MPKH, PublicKeys: []codec.IndexedPublicKey{
+				{PublicKey: keys[0], Index: 0}, {PublicKey: keys[2], Index: 2},
+			}},
+		}},
+		FixedOutputs: []codec.Output{{Amount: big.NewInt(1e18), Address: multisig}},
+	}
+	txId := unsignedTx.Id()
+
+	var submitted alephium.SubmitMultisigRequestBody
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/multisig/address":
+			var body alephium.MultisigAddressRequestBody
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, 2, body.MRequired)
+			_ = json.NewEncoder(w).Encode(alephium.MultisigAddress{Address: multisig.String()})
+		case "/multisig/build":
+			var body alephium.BuildMultisigRequestBody
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, multisig.String(), body.FromAddress)
+			_ = json.NewEncoder(w).Encode(alephium.UnsignedTransaction{UnsignedTx: unsignedTx.Hex(), TxId: txId})
+		case "/wallets/third/sign":
+			// the wallet of the third party holds the third key
+			signature, _ := signers[2].Sign(txId)
+			_ = json.NewEncoder(w).Encode(alephium.SignResponse{Signature: signature})
+		case "/multisig/submit":
+			_ = json.NewDecoder(r.Body).Decode(&submitted)
+			_ = json.NewEncoder(w).Encode(alephium.Transaction{TransactionId: txId})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := alephium.NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	created, err := client.CreateMultisigAddress(publicKeys, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, multisig.String(), created.Address)
+	_, err = client.CreateMultisigAddress(publicKeys, 4)
+	assert.NotNil(t, err)
+
+	amount, _ := alephium.ALPHFromALPHString("1")
+	destinations := []alephium.TransactionDestination{{Address: "1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi", Amount: amount}}
+	// the third party signs first, the first one last
+	signing := []string{publicKeys[0], publicKeys[2]}
+	_, err = client.BuildMultisigTransaction(multisig.String(), publicKeys, destinations)
+	assert.NotNil(t, err)
+	// the keys must be keys of the address, in its order
+	other, _ := signer.Generate()
+	_, err = client.BuildMultisigTransaction(multisig.String(), []string{publicKeys[0], other.PublicKey()}, destinations)
+	assert.NotNil(t, err)
+	_, err = client.BuildMultisigTransaction(multisig.String(), []string{publicKeys[2], publicKeys[0]}, destinations)
+	assert.NotNil(t, err)
+	tx, err := client.BuildMultisigTransaction(multisig.String(), []string{publicKeys[0], strings.ToUpper(publicKeys[2])}, destinations)
+	assert.Nil(t, err)
+	assert.Equal(t, txId, tx.TxId)
+	assert.Equal(t, signing, tx.PublicKeys)
+
+	// a signature is checked against the id of the unsigned transaction
+	otherId := strings.Repeat("00", 32)
+	signature, _ := signers[0].Sign(otherId)
+	assert.NotNil(t, tx.AddSignature(publicKeys[0], signature))
+	signature, _ = signers[2].Sign(txId)
+	assert.NotNil(t, tx.AddSignature(publicKeys[0], signature))
+	tampered := tx
+	tampered.TxId = otherId
+	assert.True(t, errors.Is(signers[0].SignMultisig(&tampered), alephium.ErrTransactionMismatch))
+	assert.True(t, errors.Is(tampered.AddSignature(publicKeys[0], signature), alephium.ErrTransactionMismatch))
+	assert.Equal(t, 0, len(tx.Signatures))
+
+	assert.Nil(t, client.SignMultisigTransaction("third", strings.ToUpper(publicKeys[2]), &tx))
+	assert.NotNil(t, signers[1].SignMultisig(&tx))
+	assert.False(t, tx.Complete())
+	_, err = client.SubmitMultisigTransaction(tx)
+	assert.NotNil(t, err)
+
+	// the envelope moves to the first party as JSON
+	b, err := json.Marshal(tx)
+	assert.Nil(t, err)
+	var received alephium.PartiallySignedTransaction
+	assert.Nil(t, json.Unmarshal(b, &received))
+	assert.Equal(t, []string{publicKeys[0]}, received.Missing())
+	assert.Nil(t, signers[0].SignMultisig(&received))
+	assert.True(t, received.Complete())
+
+	transaction, err := client.SubmitMultisigTransaction(received)
+	assert.Nil(t, err)
+	assert.Equal(t, txId, transaction.TransactionId)
+	assert.Equal(t, unsignedTx.Hex(), submitted.UnsignedTx)
+	assert.Equal(t, 2, len(submitted.Signatures))
+	for i, publicKey := range signing {
+		ok, err := signer.Verify(publicKey, txId, submitted.Signatures[i])
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+}
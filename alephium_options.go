@@ -0,0 +1,78 @@
+package alephium
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures a Client created with NewClient.
+type Option func(*clientConfig)
+
+type clientConfig struct {
+	httpClient   *http.Client
+	transport    http.RoundTripper
+	timeout      time.Duration
+	pollInterval time.Duration
+	userAgent    string
+	log          Logger
+	headers      http.Header
+	apiKey       string
+	retryPolicy  RetryPolicy
+}
+
+// WithHTTPClient uses a copy of the given http.Client to talk to the node.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *clientConfig) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRoundTripper sets the transport of the underlying http.Client.
+func WithRoundTripper(transport http.RoundTripper) Option {
+	return func(c *clientConfig) {
+		c.transport = transport
+	}
+}
+
+// WithTimeout sets the timeout of every HTTP call, DefaultTimeout if not set.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *clientConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithPollInterval sets the interval between two polls of the Wait* functions,
+// DefaultPollInterval if not set.
+func WithPollInterval(pollInterval time.Duration) Option {
+	return func(c *clientConfig) {
+		c.pollInterval = pollInterval
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every call.
+func WithUserAgent(userAgent string) Option {
+	return func(c *clientConfig) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithLogger sets the logger of the client. Nothing is logged if not set.
+func WithLogger(log Logger) Option {
+	return func(c *clientConfig) {
+		c.log = log
+	}
+}
+
+// WithHeader adds a header sent with every call.
+func WithHeader(key string, value string) Option {
+	return func(c *clientConfig) {
+		c.headers.Add(key, value)
+	}
+}
+
+// WithApiKey sets the API key sent in the ApiKeyHeader header.
+func WithApiKey(apiKey string) Option {
+	return func(c *clientConfig) {
+		c.apiKey = apiKey
+	}
+}
@@ -0,0 +1,267 @@
+package alephium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrNoHealthyNode is returned by the Pool when none of its nodes is healthy and synced
+var ErrNoHealthyNode = errors.New("no healthy node")
+
+// Pool spreads the calls over several full nodes, only using the healthy and synced ones
+// and failing over to the next one when a node goes down.
+// Wallet operations are pinned to the node holding the wallet, see Wallet.
+type Pool struct {
+	clients []*Client
+	log     Logger
+
+	mu      sync.RWMutex
+	health  []NodeHealth
+	wallets map[string]*Client
+	next    int
+}
+
+// NodeHealth is the result of the last health check of a node
+type NodeHealth struct {
+	Endpoint  string
+	Checked   bool
+	Healthy   bool
+	Synced    bool
+	LastCheck time.Time
+	Err       error
+}
+
+// NewPool creates a pool of clients, one per endpoint, all configured with the given options.
+// Nodes are considered healthy until the first health check.
+func NewPool(endpoints []string, opts ...Option) (*Pool, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("at least one endpoint is required")
+	}
+	pool := &Pool{
+		clients: make([]*Client, 0, len(endpoints)),
+		health:  make([]NodeHealth, 0, len(endpoints)),
+		wallets: make(map[string]*Client),
+	}
+	for _, endpoint := range endpoints {
+		client, err := NewClient(endpoint, opts...)
+		if err != nil {
+			return nil, err
+		}
+		pool.clients = append(pool.clients, client)
+		pool.health = append(pool.health, NodeHealth{Endpoint: endpoint, Healthy: true, Synced: true})
+	}
+	pool.log = pool.clients[0].log
+	return pool, nil
+}
+
+func (p *Pool) String() string {
+	return fmt.Sprintf("%v", p.clients)
+}
+
+// Clients returns all the clients of the pool, healthy or not
+func (p *Pool) Clients() []*Client {
+	return p.clients
+}
+
+// Health returns the result of the last health check of every node
+func (p *Pool) Health() []NodeHealth {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	health := make([]NodeHealth, len(p.health))
+	copy(health, p.health)
+	return health
+}
+
+// CheckHealth checks concurrently all the nodes, a node being healthy if it answers
+// and synced if both its clique and its peers are synced.
+func (p *Pool) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i, client := range p.clients {
+		wg.Add(1)
+		go func(i int, client *Client) {
+			defer wg.Done()
+			health := NodeHealth{Endpoint: client.endpointURI, Checked: true, LastCheck: time.Now()}
+			selfClique, err := client.GetSelfCliqueInfosCtx(ctx)
+			if err == nil {
+				health.Healthy = true
+				health.Synced = selfClique.Synced
+				if health.Synced {
+					health.Synced, err = client.IsSyncedCtx(ctx)
+					health.Healthy = err == nil
+				}
+			}
+			health.Err = err
+			if !health.Healthy || !health.Synced {
+				p.log.Debugf("Node %s is not usable (healthy=%v, synced=%v): %v", client, health.Healthy, health.Synced, err)
+			}
+			p.mu.Lock()
+			p.health[i] = health
+			p.mu.Unlock()
+		}(i, client)
+	}
+	wg.Wait()
+}
+
+// Run checks the health of the nodes every interval, until the context is done.
+func (p *Pool) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.CheckHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Healthy returns the healthy and synced clients, starting at a different one at every call
+func (p *Pool) Healthy() []*Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	healthy := make([]*Client, 0, len(p.clients))
+	for i := range p.clients {
+		idx := (p.next + i) % len(p.clients)
+		if p.health[idx].Healthy && p.health[idx].Synced {
+			healthy = append(healthy, p.clients[idx])
+		}
+	}
+	p.next = (p.next + 1) % len(p.clients)
+	return healthy
+}
+
+// Do calls f with a healthy and synced client, failing over to the next one
+// if the node is unreachable or unavailable. Meant for read operations.
+func (p *Pool) Do(ctx context.Context, f func(ctx context.Context, client *Client) error) error {
+	healthy := p.Healthy()
+	if len(healthy) == 0 {
+		return ErrNoHealthyNode
+	}
+	var err error
+	for _, client := range healthy {
+		err = f(ctx, client)
+		if err == nil || !isFailoverError(ctx, err) {
+			return err
+		}
+		p.log.Debugf("Node %s failed, failing over: %v", client, err)
+		p.markUnhealthy(client, err)
+	}
+	return err
+}
+
+func (p *Pool) markUnhealthy(client *Client, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, c := range p.clients {
+		if c == client {
+			p.health[i].Checked = true
+			p.health[i].Healthy = false
+			p.health[i].LastCheck = time.Now()
+			p.health[i].Err = err
+		}
+	}
+}
+
+// isFailoverError returns true if the error is due to the node rather than to the request
+func isFailoverError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// Wallet returns the client of the node holding the given wallet, looking for it
+// on every node the first time and pinning it afterwards.
+func (p *Pool) Wallet(ctx context.Context, walletName string) (*Client, error) {
+	p.mu.RLock()
+	client, ok := p.wallets[walletName]
+	p.mu.RUnlock()
+	if ok {
+		return client, nil
+	}
+	var lastErr error
+	for _, client := range p.clients {
+		exist, err := client.CheckWalletExistCtx(ctx, walletName)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if exist {
+			p.mu.Lock()
+			p.wallets[walletName] = client
+			p.mu.Unlock()
+			return client, nil
+		}
+	}
+	if lastErr != nil {
+		return nil, fmt.Errorf("wallet %s not found on any reachable node: %w", walletName, lastErr)
+	}
+	return nil, fmt.Errorf("wallet %s: %w", walletName, ErrNotFound)
+}
+
+// PinWallet pins the given wallet to the node with the given endpoint,
+// e.g. after creating or restoring the wallet on it.
+func (p *Pool) PinWallet(walletName string, endpoint string) error {
+	for _, client := range p.clients {
+		if client.endpointURI == endpoint {
+			p.mu.Lock()
+			p.wallets[walletName] = client
+			p.mu.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("endpoint %s is not part of the pool", endpoint)
+}
+
+// GetSelfCliqueInfosCtx is like Client.GetSelfCliqueInfosCtx, on a healthy node
+func (p *Pool) GetSelfCliqueInfosCtx(ctx context.Context) (SelfCliqueInfo, error) {
+	var selfCliqueInfo SelfCliqueInfo
+	err := p.Do(ctx, func(ctx context.Context, client *Client) error {
+		var err error
+		selfCliqueInfo, err = client.GetSelfCliqueInfosCtx(ctx)
+		return err
+	})
+	return selfCliqueInfo, err
+}
+
+// GetAddressBalanceCtx is like Client.GetAddressBalanceCtx, on a healthy node
+func (p *Pool) GetAddressBalanceCtx(ctx context.Context, address string, utxosLimit int) (AddressUtxoBalance, error) {
+	var addressBalance AddressUtxoBalance
+	err := p.Do(ctx, func(ctx context.Context, client *Client) error {
+		var err error
+		addressBalance, err = client.GetAddressBalanceCtx(ctx, address, utxosLimit)
+		return err
+	})
+	return addressBalance, err
+}
+
+// GetAddressUtxosCtx is like Client.GetAddressUtxosCtx, on a healthy node
+func (p *Pool) GetAddressUtxosCtx(ctx context.Context, address string, utxosLimit int) (AddressUtxosList, error) {
+	var utxosList AddressUtxosList
+	err := p.Do(ctx, func(ctx context.Context, client *Client) error {
+		var err error
+		utxosList, err = client.GetAddressUtxosCtx(ctx, address, utxosLimit)
+		return err
+	})
+	return utxosList, err
+}
+
+// GetTransactionStatusCtx is like Client.GetTransactionStatusCtx, on a healthy node
+func (p *Pool) GetTransactionStatusCtx(ctx context.Context, transactionId string, fromGroup int, toGroup int) (TransactionStatus, error) {
+	var transactionStatus TransactionStatus
+	err := p.Do(ctx, func(ctx context.Context, client *Client) error {
+		var err error
+		transactionStatus, err = client.GetTransactionStatusCtx(ctx, transactionId, fromGroup, toGroup)
+		return err
+	})
+	return transactionStatus, err
+}
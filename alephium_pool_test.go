@@ -0,0 +1,115 @@
+package alephium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newFakeNode(synced *bool, up *bool, wallet string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !*up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/infos/self-clique":
+			_, _ = fmt.Fprintf(w, `{"synced":%v,"groups":4}`, *synced)
+		case "/infos/inter-clique-peer-info":
+			_, _ = w.Write([]byte(`[]`))
+		case "/addresses/1DrDyTr9RpRsQnDnXo2YRiPzPW4ooHX5LLoqXrqfMrpQH/group":
+			_, _ = w.Write([]byte(`{"group":3}`))
+		case "/wallets/" + wallet:
+			_, _ = fmt.Fprintf(w, `{"walletName":"%s","locked":false}`, wallet)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"detail":"not found"}`))
+		}
+	}))
+}
+
+func TestPoolFailover(t *testing.T) {
+
+	synced1, up1 := false, true
+	synced2, up2 := true, true
+	node1 := newFakeNode(&synced1, &up1, "wallet-1")
+	defer node1.Close()
+	node2 := newFakeNode(&synced2, &up2, "wallet-2")
+	defer node2.Close()
+
+	pool, err := NewPool([]string{node1.URL, node2.URL})
+	assert.Nil(t, err)
+	assert.Len(t, pool.Healthy(), 2)
+
+	pool.CheckHealth(context.Background())
+	healthy := pool.Healthy()
+	assert.Len(t, healthy, 1)
+	assert.Equal(t, node2.URL, healthy[0].String())
+
+	var used []string
+	getGroup := func() error {
+		return pool.Do(context.Background(), func(ctx context.Context, client *Client) error {
+			used = append(used, client.String())
+			_, err := client.GetAddressGroupCtx(ctx, "1DrDyTr9RpRsQnDnXo2YRiPzPW4ooHX5LLoqXrqfMrpQH")
+			return err
+		})
+	}
+	assert.Nil(t, getGroup())
+	assert.Equal(t, []string{node2.URL}, used)
+
+	// node1 catches up, node2 goes down: the call fails over to node1
+	synced1 = true
+	pool.CheckHealth(context.Background())
+	up2 = false
+	used = nil
+	assert.Nil(t, getGroup())
+	assert.Nil(t, getGroup())
+	assert.Equal(t, node1.URL, used[len(used)-1])
+	assert.Len(t, pool.Healthy(), 1)
+
+	up1 = false
+	err = getGroup()
+	assert.True(t, errors.Is(err, ErrServiceUnavailable))
+	assert.True(t, errors.Is(getGroup(), ErrNoHealthyNode))
+
+	// errors which are not due to the node are not failed over
+	up1, up2 = true, true
+	pool.CheckHealth(context.Background())
+	_, err = pool.GetTransactionStatusCtx(context.Background(), "unknown", 0, 0)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.Len(t, pool.Healthy(), 2)
+}
+
+func TestPoolWalletPinning(t *testing.T) {
+
+	synced, up := true, true
+	node1 := newFakeNode(&synced, &up, "wallet-1")
+	defer node1.Close()
+	node2 := newFakeNode(&synced, &up, "wallet-2")
+	defer node2.Close()
+
+	pool, err := NewPool([]string{node1.URL, node2.URL})
+	assert.Nil(t, err)
+
+	client, err := pool.Wallet(context.Background(), "wallet-2")
+	assert.Nil(t, err)
+	assert.Equal(t, node2.URL, client.String())
+
+	client, err = pool.Wallet(context.Background(), "wallet-1")
+	assert.Nil(t, err)
+	assert.Equal(t, node1.URL, client.String())
+
+	_, err = pool.Wallet(context.Background(), "wallet-3")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	assert.Nil(t, pool.PinWallet("wallet-3", node2.URL))
+	client, err = pool.Wallet(context.Background(), "wallet-3")
+	assert.Nil(t, err)
+	assert.Equal(t, node2.URL, client.String())
+	assert.NotNil(t, pool.PinWallet("wallet-3", "http://unknown:12973"))
+}
@@ -0,0 +1,124 @@
+package alephium
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures the automatic retries of the calls failing with a transport error
+// or with a 429, 502, 503 or 504 status code.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. No retry if <= 1.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts, including the one hinted by Retry-After
+	MaxBackoff time.Duration
+	// Multiplier is applied to the delay after every attempt
+	Multiplier float64
+	// Jitter randomizes the delay by +/- the given fraction, e.g. 0.2 for +/- 20%
+	Jitter float64
+	// RetryNonIdempotent enables the retries of POST, PUT and DELETE calls too, like SubmitTransaction.
+	// Use with caution, the node might have processed the first attempt.
+	RetryNonIdempotent bool
+	// OnRetry, if not nil, is called before every retry
+	OnRetry func(RetryEvent)
+}
+
+// RetryEvent describes a failed attempt about to be retried.
+type RetryEvent struct {
+	Method  string
+	Path    string
+	Attempt int
+	Err     error
+	Backoff time.Duration
+}
+
+// DefaultRetryPolicy retries idempotent calls up to 4 times, from 500ms to 10s apart.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// WithRetryPolicy enables automatic retries with the given policy. No retry by default.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *clientConfig) {
+		c.retryPolicy = policy
+	}
+}
+
+// shouldRetry returns whether the given attempt, which failed with err, should be retried and after which delay.
+func (p RetryPolicy) shouldRetry(ctx context.Context, req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return 0, false
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead && !p.RetryNonIdempotent {
+		return 0, false
+	}
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		switch apiError.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		default:
+			return 0, false
+		}
+	} else if resp != nil {
+		// the node answered successfully but the body couldn't be decoded, no point in retrying
+		return 0, false
+	}
+
+	backoff := p.backoff(attempt)
+	if retryAfter, ok := parseRetryAfter(resp); ok {
+		backoff = retryAfter
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff, true
+}
+
+// backoff computes the exponential backoff, with jitter, after the given attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(backoff)
+}
+
+// parseRetryAfter reads the Retry-After header, either in seconds or as an HTTP date
+func parseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	retryAfter := resp.Header.Get("Retry-After")
+	if retryAfter == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(retryAfter); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,265 @@
+package alephium
+
+import (
+	"context"
+	"errors"
+	"github.com/sqooba/go-common/logging"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextCancellation(t *testing.T) {
+
+	log := logging.NewLogger()
+	release := make(chan struct{})
+	defer close(release)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+
+	alephiumClient, err := New(ts.URL, log)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = alephiumClient.GetSelfCliqueInfosCtx(ctx)
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+type countingRoundTripper struct {
+	calls int
+}
+
+func (c *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.calls++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNewClientOptions(t *testing.T) {
+
+	var headers http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"version":"v1.1.13"}`))
+	}))
+	defer ts.Close()
+
+	roundTripper := &countingRoundTripper{}
+	alephiumClient, err := NewClient(ts.URL,
+		WithApiKey(TestApiKey),
+		WithUserAgent("alephium-go-client-test"),
+		WithHeader("X-Custom", "custom"),
+		WithRoundTripper(roundTripper),
+		WithPollInterval(10*time.Millisecond),
+		WithLogger(logging.NewLogger()))
+	assert.Nil(t, err)
+	assert.Equal(t, 10*time.Millisecond, alephiumClient.sleepTime)
+
+	nodeInfo, err := alephiumClient.GetNodeInfos()
+	assert.Nil(t, err)
+	assert.Equal(t, "v1.1.13", nodeInfo.Version)
+	assert.Equal(t, 1, roundTripper.calls)
+	assert.Equal(t, TestApiKey, headers.Get(ApiKeyHeader))
+	assert.Equal(t, "alephium-go-client-test", headers.Get(UserAgentHeader))
+	assert.Equal(t, "custom", headers.Get("X-Custom"))
+
+	_, err = NewClient("not an url")
+	assert.NotNil(t, err)
+}
+
+func TestTypedErrors(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/wallets/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"resource":"missing","detail":"missing not found"}`))
+		case "/wallets/locked/balances":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"detail":"Wallet is locked"}`))
+		case "/wallets/existing":
+			_, _ = w.Write([]byte(`{"walletName":"existing","locked":false}`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	alephiumClient, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	_, err = alephiumClient.GetWalletStatus("missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	var apiError *APIError
+	assert.True(t, errors.As(err, &apiError))
+	assert.Equal(t, http.StatusNotFound, apiError.StatusCode)
+	assert.Equal(t, "missing not found", apiError.Detail)
+	var errorDetail ErrorDetail
+	assert.True(t, errors.As(err, &errorDetail))
+	assert.Equal(t, "missing", errorDetail.Resource)
+
+	exist, err := alephiumClient.CheckWalletExist("missing")
+	assert.Nil(t, err)
+	assert.False(t, exist)
+	exist, err = alephiumClient.CheckWalletExist("existing")
+	assert.Nil(t, err)
+	assert.True(t, exist)
+
+	_, err = alephiumClient.GetWalletBalances("locked")
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+	assert.True(t, errors.Is(err, ErrWalletLocked))
+
+	// empty body must not be treated as a success
+	_, err = alephiumClient.GetSelfCliqueInfos()
+	assert.True(t, errors.Is(err, ErrServiceUnavailable))
+	assert.False(t, errors.Is(err, ErrWalletLocked))
+}
+
+func TestRetryPolicy(t *testing.T) {
+
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		if calls < 3 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"detail":"syncing"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	var retries []RetryEvent
+	policy := RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		Multiplier:     2,
+		Jitter:         0.2,
+		OnRetry: func(event RetryEvent) {
+			retries = append(retries, event)
+		},
+	}
+	alephiumClient, err := NewClient(ts.URL, WithRetryPolicy(policy))
+	assert.Nil(t, err)
+
+	peers, err := alephiumClient.GetInterCliquePeerInfos()
+	assert.Nil(t, err)
+	assert.Empty(t, peers)
+	assert.Equal(t, 3, calls)
+	assert.Len(t, retries, 2)
+	assert.Equal(t, http.MethodGet, retries[0].Method)
+	assert.Equal(t, "/infos/inter-clique-peer-info", retries[0].Path)
+	assert.Equal(t, 10*time.Millisecond, retries[0].Backoff)
+	assert.True(t, errors.Is(retries[1].Err, ErrServiceUnavailable))
+
+	// POSTs are not retried unless explicitly enabled
+	calls = 0
+	_, err = alephiumClient.SubmitTransaction("unsigned", "signature")
+	assert.True(t, errors.Is(err, ErrServiceUnavailable))
+	assert.Equal(t, 1, calls)
+
+	policy.RetryNonIdempotent = true
+	alephiumClient, err = NewClient(ts.URL, WithRetryPolicy(policy))
+	assert.Nil(t, err)
+	calls = 0
+	_, err = alephiumClient.SubmitTransaction("unsigned", "signature")
+	assert.NotNil(t, err) // body `[]` is not a transaction
+	assert.Equal(t, 3, calls)
+}
+
+func TestAddressValidation(t *testing.T) {
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"txId":"tx","fromGroup":0,"toGroup":0,"unsignedTx":"unsigned"}`))
+	}))
+	defer ts.Close()
+
+	alephiumClient, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+	amount, _ := ALPHFromALPHString("1")
+
+	_, err = alephiumClient.Transfer("wallet", "not-an-address", amount)
+	assert.True(t, errors.Is(err, ErrInvalidAddress))
+	_, err = alephiumClient.SweepAll("wallet", "")
+	assert.True(t, errors.Is(err, ErrInvalidAddress))
+	_, err = alephiumClient.BuildTransaction("publicKey", []TransactionDestination{
+		{Address: "1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi", Amount: amount},
+		{Address: "1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3Yvg", Amount: amount},
+	})
+	assert.True(t, errors.Is(err, ErrInvalidAddress))
+	assert.Equal(t, 0, requests)
+
+	_, err = alephiumClient.Transfer("wallet", "1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi", amount)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, requests)
+}
+
+func TestTransactionOptions(t *testing.T) {
+
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"txId":"tx","fromGroup":0,"toGroup":0,"unsignedTx":"unsigned"}`))
+	}))
+	defer ts.Close()
+
+	alephiumClient, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+	amount, _ := ALPHFromALPHString("1")
+	gasPrice, _ := ALPHFromALPHString("0.0000002")
+	token := Token{Id: strings.Repeat("ab", 32), Amount: U256{Value: big.NewInt(42)}}
+
+	destination := TransactionDestination{
+		Address:  "1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi",
+		Amount:   amount,
+		Tokens:   []Token{token},
+		LockTime: 1640995200000,
+	}
+	_, err = alephiumClient.BuildTransactionWithOptions("publicKey", []TransactionDestination{destination},
+		TransactionOptions{GasAmount: 30000, GasPrice: &gasPrice})
+	assert.Nil(t, err)
+	_, err = alephiumClient.TransferWithOptions("wallet", TransferDestination{Address: destination.Address, Amount: amount}, TransactionOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(bodies))
+	assert.JSONEq(t, `{"fromPublicKey":"publicKey","destinations":[{"address":"1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi",
+		"amount":"1000000000000000000","tokens":[{"id":"`+token.Id+`","amount":"42"}],"lockTime":1640995200000}],
+		"gas":30000,"gasPrice":"200000000000"}`, bodies[0])
+	assert.JSONEq(t, `{"destinations":[{"address":"1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi","amount":"1000000000000000000"}]}`, bodies[1])
+
+	// invalid options are rejected before calling the node
+	_, err = alephiumClient.BuildTransactionWithOptions("publicKey", []TransactionDestination{destination}, TransactionOptions{GasAmount: 10})
+	assert.NotNil(t, err)
+	invalidToken := destination
+	invalidToken.Tokens = []Token{{Id: "ab", Amount: U256{Value: big.NewInt(1)}}}
+	_, err = alephiumClient.BuildTransaction("publicKey", []TransactionDestination{invalidToken})
+	assert.NotNil(t, err)
+	_, err = alephiumClient.TransferWithOptions("wallet", TransferDestination{Address: destination.Address, Amount: amount, LockTime: -1}, TransactionOptions{})
+	assert.NotNil(t, err)
+	_, err = alephiumClient.TransferWithOptions("wallet", TransferDestination{Address: destination.Address, Amount: amount,
+		Tokens: []TransferToken{{Id: token.Id, Amount: "4.2"}}}, TransactionOptions{})
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, len(bodies))
+}
@@ -1,33 +1,72 @@
 package alephium
 
 import (
+	"bytes"
 	"context"
-	"time"
+	"encoding/hex"
+	"fmt"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/codec"
+	"strings"
 )
 
 const (
 	TxConfirmed = "confirmed"
 )
 
-// GetUnconfirmedTransactions gets the list of unconfirmed transactions
-func (a *Client) GetUnconfirmedTransactions() error {
+// GetUnconfirmedTransactions gets the transactions of the mempool, grouped by chain
+func (a *Client) GetUnconfirmedTransactions() ([]UnconfirmedTransactions, error) {
+	return a.GetUnconfirmedTransactionsCtx(context.Background())
+}
 
-	var errorDetail ErrorDetail
+// GetUnconfirmedTransactionsCtx is like GetUnconfirmedTransactions, with a context
+func (a *Client) GetUnconfirmedTransactionsCtx(ctx context.Context) ([]UnconfirmedTransactions, error) {
 
-	_, err := a.slingClient.New().Get("transactions/unconfirmed").
-		Receive(nil, &errorDetail)
+	var unconfirmedTransactions []UnconfirmedTransactions
+	err := a.receive(ctx, a.slingClient.New().Get("transactions/unconfirmed"), &unconfirmedTransactions)
 
-	return relevantError(err, errorDetail)
+	return unconfirmedTransactions, err
 }
 
 type BuildTransactionBodyRequest struct {
 	FromPublicKey string                   `json:"fromPublicKey"`
 	Destinations  []TransactionDestination `json:"destinations"`
+	Gas           int                      `json:"gas,omitempty"`
+	GasPrice      *ALPH                    `json:"gasPrice,omitempty"`
 }
 
 type TransactionDestination struct {
-	Address string `json:"address"`
-	Amount  ALPH   `json:"amount"`
+	Address string  `json:"address"`
+	Amount  ALPH    `json:"amount"`
+	Tokens  []Token `json:"tokens,omitempty"`
+	// LockTime is the timestamp, in milliseconds, until which the output can't be spent, 0 if not locked
+	LockTime int64 `json:"lockTime,omitempty"`
+}
+
+// Validate checks the address, the tokens and the lock time of the destination, without calling the node
+func (d TransactionDestination) Validate() error {
+	return validateDestination(d.Address, d.Tokens, d.LockTime)
+}
+
+// TransactionOptions are the optional gas settings of a transaction, estimated by the node if not set
+type TransactionOptions struct {
+	// GasAmount is the gas of the transaction, between codec.MinimalGas and codec.MaximalGasPerTx
+	GasAmount int
+	// GasPrice is the price of a unit of gas
+	GasPrice *ALPH
+}
+
+// Validate checks the gas amount and price, without calling the node
+func (o TransactionOptions) Validate() error {
+	if o.GasAmount != 0 && (o.GasAmount < int(codec.MinimalGas) || o.GasAmount > int(codec.MaximalGasPerTx)) {
+		return fmt.Errorf("invalid gas amount %d, must be between %d and %d", o.GasAmount, codec.MinimalGas, codec.MaximalGasPerTx)
+	}
+	if o.GasPrice != nil && (o.GasPrice.Amount == nil || o.GasPrice.Amount.Sign() <= 0) {
+		return fmt.Errorf("invalid gas price %s", o.GasPrice)
+	}
+	return nil
 }
 
 type UnsignedTransaction struct {
@@ -39,19 +78,154 @@ type UnsignedTransaction struct {
 
 // BuildTransaction builds an unsigned transaction
 func (a *Client) BuildTransaction(publicKey string, destinations []TransactionDestination) (UnsignedTransaction, error) {
+	return a.BuildTransactionCtx(context.Background(), publicKey, destinations)
+}
+
+// BuildTransactionCtx is like BuildTransaction, with a context
+func (a *Client) BuildTransactionCtx(ctx context.Context, publicKey string, destinations []TransactionDestination) (UnsignedTransaction, error) {
+	return a.BuildTransactionWithOptionsCtx(ctx, publicKey, destinations, TransactionOptions{})
+}
+
+// BuildTransactionWithOptions is like BuildTransaction, with the gas amount and price of the transaction
+func (a *Client) BuildTransactionWithOptions(publicKey string, destinations []TransactionDestination, opts TransactionOptions) (UnsignedTransaction, error) {
+	return a.BuildTransactionWithOptionsCtx(context.Background(), publicKey, destinations, opts)
+}
+
+// BuildTransactionWithOptionsCtx is like BuildTransactionWithOptions, with a context
+func (a *Client) BuildTransactionWithOptionsCtx(ctx context.Context, publicKey string, destinations []TransactionDestination,
+	opts TransactionOptions) (UnsignedTransaction, error) {
 
 	var unsignedTx UnsignedTransaction
-	var errorDetail ErrorDetail
 
+	for _, destination := range destinations {
+		if err := destination.Validate(); err != nil {
+			return unsignedTx, err
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		return unsignedTx, err
+	}
 	body := BuildTransactionBodyRequest{
 		FromPublicKey: publicKey,
 		Destinations:  destinations,
+		Gas:           opts.GasAmount,
+		GasPrice:      opts.GasPrice,
+	}
+
+	err := a.receive(ctx, a.slingClient.New().Post("transactions/build").BodyJSON(body), &unsignedTx)
+
+	return unsignedTx, err
+}
+
+// VerifyUnsignedTransaction decodes the unsigned transaction and checks, before signing it, that it
+// pays exactly the destinations, with their tokens and lock time, runs no script, spends only outputs
+// of the public key and sends the change back to it.
+// An error wrapping ErrTransactionMismatch is returned otherwise.
+func VerifyUnsignedTransaction(unsignedTx UnsignedTransaction, fromPublicKey string, destinations []TransactionDestination) error {
+	tx, err := codec.DecodeUnsignedTxHex(unsignedTx.UnsignedTx)
+	if err != nil {
+		return err
+	}
+	if tx.Id() != unsignedTx.TxId {
+		return fmt.Errorf("%w: tx id %s, computed %s", ErrTransactionMismatch, unsignedTx.TxId, tx.Id())
+	}
+	if tx.Script != nil {
+		return fmt.Errorf("%w: unexpected script", ErrTransactionMismatch)
+	}
+	publicKey, err := hex.DecodeString(fromPublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key %s: %v", fromPublicKey, err)
+	}
+	for i, input := range tx.Inputs {
+		keys := input.UnlockScript.PublicKeys
+		if input.UnlockScript.Type != codec.UnlockP2PKH || !bytes.Equal(keys[0].PublicKey, publicKey) {
+			return fmt.Errorf("%w: input %d is not unlocked by %s", ErrTransactionMismatch, i, fromPublicKey)
+		}
+	}
+
+	changeAddress := address.NewP2PKH(publicKey).String()
+	matched := make([]bool, len(tx.FixedOutputs))
+	for _, destination := range destinations {
+		found := false
+		for i, output := range tx.FixedOutputs {
+			if !matched[i] && output.Address.String() == destination.Address &&
+				destination.Amount.Amount != nil && output.Amount.Cmp(destination.Amount.Amount) == 0 &&
+				output.LockTime == uint64(destination.LockTime) && sameTokens(output.Tokens, destination.Tokens) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w: no output of %s to %s", ErrTransactionMismatch, destination.Amount, destination.Address)
+		}
+	}
+	for i, output := range tx.FixedOutputs {
+		if !matched[i] && output.Address.String() != changeAddress {
+			return fmt.Errorf("%w: unexpected output %d of %s to %s", ErrTransactionMismatch, i, output.Amount, output.Address)
+		}
 	}
+	return nil
+}
 
-	_, err := a.slingClient.New().Post("transactions/build").
-		BodyJSON(body).Receive(&unsignedTx, &errorDetail)
+// VerifySignature checks the hex encoded signature of the hex encoded hash against the hex encoded public key,
+// with the signature scheme of the node
+func VerifySignature(publicKey string, hash string, signature string) (bool, error) {
+	pk, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return false, fmt.Errorf("invalid public key: %v", err)
+	}
+	key, err := secp256k1.ParsePubKey(pk)
+	if err != nil {
+		return false, err
+	}
+	h, err := hex.DecodeString(hash)
+	if err != nil {
+		return false, fmt.Errorf("invalid hash: %v", err)
+	}
+	if len(h) != codec.HashLength {
+		return false, fmt.Errorf("invalid hash length %d, expected %d", len(h), codec.HashLength)
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return false, fmt.Errorf("invalid signature: %v", err)
+	}
+	if len(sig) != 64 {
+		return false, fmt.Errorf("invalid signature length %d, expected 64", len(sig))
+	}
+	var r, s secp256k1.ModNScalar
+	if overflow := r.SetByteSlice(sig[:32]); overflow || r.IsZero() {
+		return false, nil
+	}
+	if overflow := s.SetByteSlice(sig[32:]); overflow || s.IsZero() {
+		return false, nil
+	}
+	// the node only accepts canonical signatures
+	if s.IsOverHalfOrder() {
+		return false, nil
+	}
+	return ecdsa.NewSignature(&r, &s).Verify(h, key), nil
+}
 
-	return unsignedTx, relevantError(err, errorDetail)
+// sameTokens is true if the output carries exactly the tokens of the destination, in any order
+func sameTokens(outputTokens []codec.Token, tokens []Token) bool {
+	if len(outputTokens) != len(tokens) {
+		return false
+	}
+	matched := make([]bool, len(outputTokens))
+	for _, token := range tokens {
+		found := false
+		for i, outputToken := range outputTokens {
+			if !matched[i] && hex.EncodeToString(outputToken.Id) == strings.ToLower(token.Id) &&
+				token.Amount.Value != nil && outputToken.Amount.Cmp(token.Amount.Value) == 0 {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
 
 type SubmitTransactionBodyRequest struct {
@@ -61,18 +235,21 @@ type SubmitTransactionBodyRequest struct {
 
 // SubmitTransaction submit a previously built and signed transaction
 func (a *Client) SubmitTransaction(unsignedTxId string, signature string) (Transaction, error) {
+	return a.SubmitTransactionCtx(context.Background(), unsignedTxId, signature)
+}
+
+// SubmitTransactionCtx is like SubmitTransaction, with a context
+func (a *Client) SubmitTransactionCtx(ctx context.Context, unsignedTxId string, signature string) (Transaction, error) {
 
 	var tx Transaction
-	var errorDetail ErrorDetail
 
 	params := SubmitTransactionBodyRequest{
 		UnsignedTx: unsignedTxId,
 		Signature:  signature,
 	}
-	_, err := a.slingClient.New().Post("transactions/submit").
-		BodyJSON(params).Receive(&tx, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Post("transactions/submit").BodyJSON(params), &tx)
 
-	return tx, relevantError(err, errorDetail)
+	return tx, err
 }
 
 type TransactionStatusRequestParams struct {
@@ -83,19 +260,36 @@ type TransactionStatusRequestParams struct {
 
 // GetTransactionStatus gets the status of a given transaction
 func (a *Client) GetTransactionStatus(transactionId string, fromGroup int, toGroup int) (TransactionStatus, error) {
+	return a.GetTransactionStatusCtx(context.Background(), transactionId, fromGroup, toGroup)
+}
+
+// GetTransactionStatusCtx is like GetTransactionStatus, with a context
+func (a *Client) GetTransactionStatusCtx(ctx context.Context, transactionId string, fromGroup int, toGroup int) (TransactionStatus, error) {
 
 	var transactionStatus TransactionStatus
-	var errorDetail ErrorDetail
 
 	params := TransactionStatusRequestParams{
 		TransactionId: transactionId,
 		FromGroup:     fromGroup,
 		ToGroup:       toGroup,
 	}
-	_, err := a.slingClient.New().Get("transactions/status").
-		QueryStruct(params).Receive(&transactionStatus, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Get("transactions/status").QueryStruct(params), &transactionStatus)
+
+	return transactionStatus, err
+}
 
-	return transactionStatus, relevantError(err, errorDetail)
+// GetTxStatus gets the typed status of a given transaction
+func (a *Client) GetTxStatus(transactionId string, fromGroup int, toGroup int) (TxStatus, error) {
+	return a.GetTxStatusCtx(context.Background(), transactionId, fromGroup, toGroup)
+}
+
+// GetTxStatusCtx is like GetTxStatus, with a context
+func (a *Client) GetTxStatusCtx(ctx context.Context, transactionId string, fromGroup int, toGroup int) (TxStatus, error) {
+	status, err := a.GetTransactionStatusCtx(ctx, transactionId, fromGroup, toGroup)
+	if err != nil {
+		return nil, err
+	}
+	return status.TxStatus()
 }
 
 // WaitForTransactionConfirmed waits until the transaction is confirmed
@@ -113,16 +307,18 @@ func (a *Client) WaitForTransactionStatus(ctx context.Context, status string, tr
 		default:
 
 		}
-		tx, err := a.GetTransactionStatus(transactionId, fromGroup, toGroup)
+		tx, err := a.GetTransactionStatusCtx(ctx, transactionId, fromGroup, toGroup)
 		if err != nil {
 			return false, err
 		}
 		txStatus = tx.Type
-		if txStatus == status {
+		if normalizeTxStatusType(txStatus) == normalizeTxStatusType(status) {
 			return true, nil
 		} else {
 			a.log.Debugf("Tx %s not %s yet, sleeping %s", transactionId, status, a.sleepTime)
-			time.Sleep(a.sleepTime)
+			if err := sleep(ctx, a.sleepTime); err != nil {
+				return false, err
+			}
 		}
 	}
 }
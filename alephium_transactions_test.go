@@ -2,8 +2,13 @@ package alephium
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"github.com/sqooba/go-common/logging"
 	"github.com/stretchr/testify/assert"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/codec"
+	"math/big"
 	"testing"
 )
 
@@ -86,3 +91,82 @@ func TestTransactionE2E(t *testing.T) {
 	//assert.Nil(t, err)
 	//assert.True(t, ok)
 }
+
+func TestVerifyUnsignedTransaction(t *testing.T) {
+
+	fromPublicKey := "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	publicKey, _ := hex.DecodeString(fromPublicKey)
+	to, err := address.Decode("1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi")
+	assert.Nil(t, err)
+	amount, _ := ALPHFromALPHString("1")
+
+	tx := codec.UnsignedTx{
+		GasAmount: 20000,
+		GasPrice:  big.NewInt(100000000000),
+		Inputs: []codec.Input{{
+			Key:          make([]byte, codec.HashLength),
+			UnlockScript: codec.UnlockScript{Type: codec.UnlockP2PKH, PublicKeys: []codec.IndexedPublicKey{{PublicKey: publicKey}}},
+		}},
+		FixedOutputs: []codec.Output{
+			{Amount: amount.Amount, Address: to},
+			{Amount: big.NewInt(42), Address: address.NewP2PKH(publicKey)},
+		},
+	}
+	unsignedTx := UnsignedTransaction{UnsignedTx: tx.Hex(), TxId: tx.Id()}
+	destinations := []TransactionDestination{{Address: to.String(), Amount: amount}}
+
+	assert.Nil(t, VerifyUnsignedTransaction(unsignedTx, fromPublicKey, destinations))
+
+	// wrong amount
+	destinations[0].Amount, _ = ALPHFromALPHString("2")
+	assert.True(t, errors.Is(VerifyUnsignedTransaction(unsignedTx, fromPublicKey, destinations), ErrTransactionMismatch))
+	destinations[0].Amount = amount
+
+	// wrong tx id
+	wrongId := unsignedTx
+	wrongId.TxId = "bdaf9dc514ce7d34b6474b8ca10a3dfb93ba997cb9d5ff1ea724ebe2af48abe5"
+	assert.True(t, errors.Is(VerifyUnsignedTransaction(wrongId, fromPublicKey, destinations), ErrTransactionMismatch))
+
+	// a script is run
+	withScript := tx
+	withScript.Script = []byte{1, 1, 0, 0, 0, 0, 1, 2, 0xa0}
+	unsignedTx = UnsignedTransaction{UnsignedTx: withScript.Hex(), TxId: withScript.Id()}
+	assert.True(t, errors.Is(VerifyUnsignedTransaction(unsignedTx, fromPublicKey, destinations), ErrTransactionMismatch))
+
+	// the change goes to someone else
+	tx.FixedOutputs[1].Address, _ = address.Decode("1Ambgi1jNRcBcdDUSfyrY2uQXdHpJs3zfc7Nmt6NcpBbL")
+	unsignedTx = UnsignedTransaction{UnsignedTx: tx.Hex(), TxId: tx.Id()}
+	assert.True(t, errors.Is(VerifyUnsignedTransaction(unsignedTx, fromPublicKey, destinations), ErrTransactionMismatch))
+}
+
+func TestTxStatus(t *testing.T) {
+
+	for _, s := range []string{
+		`{"type":"confirmed","blockHash":"h","blockIndex":1,"chainConfirmations":3,"fromGroupConfirmations":4,"toGroupConfirmations":2}`,
+		`{"type":"Confirmed","blockHash":"h","blockIndex":1,"chainConfirmations":3,"fromGroupConfirmations":4,"toGroupConfirmations":2}`,
+	} {
+		status, err := UnmarshalTxStatus([]byte(s))
+		assert.Nil(t, err)
+		confirmed, ok := status.(Confirmed)
+		assert.True(t, ok)
+		assert.Equal(t, "h", confirmed.BlockHash)
+		assert.Equal(t, 3, confirmed.ChainConfirmations)
+		assert.True(t, status.IsFinal(2))
+		assert.False(t, status.IsFinal(3))
+	}
+
+	for _, s := range []string{`{"type":"mem-pooled"}`, `{"type":"MemPooled"}`} {
+		status, err := UnmarshalTxStatus([]byte(s))
+		assert.Nil(t, err)
+		assert.Equal(t, MemPooled{}, status)
+		assert.False(t, status.IsFinal(0))
+	}
+	for _, s := range []string{`{"type":"tx-not-found"}`, `{"type":"TxNotFound"}`} {
+		status, err := UnmarshalTxStatus([]byte(s))
+		assert.Nil(t, err)
+		assert.Equal(t, TxNotFound{}, status)
+	}
+
+	_, err := UnmarshalTxStatus([]byte(`{"type":"unknown"}`))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,281 @@
+package alephium
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultNotFoundTimeout is how long a transaction may stay unknown to the node before TxEventNotFound
+	DefaultNotFoundTimeout = time.Minute
+)
+
+// TxEventKind is the kind of a TxEvent
+type TxEventKind int
+
+const (
+	// TxEventMemPooled is fired when the transaction enters the mempool
+	TxEventMemPooled TxEventKind = iota + 1
+	// TxEventConfirmed is fired when the transaction is in a block, and every time its confirmations change
+	TxEventConfirmed
+	// TxEventFinalized is fired once the transaction has the required confirmations, it is not watched anymore
+	TxEventFinalized
+	// TxEventDropped is fired when a transaction seen before is unknown to the node again,
+	// e.g. evicted from the mempool or removed by a reorg. It is still watched.
+	TxEventDropped
+	// TxEventNotFound is fired when the transaction stayed unknown to the node for the not found timeout,
+	// it is not watched anymore
+	TxEventNotFound
+)
+
+func (k TxEventKind) String() string {
+	switch k {
+	case TxEventMemPooled:
+		return "mem-pooled"
+	case TxEventConfirmed:
+		return "confirmed"
+	case TxEventFinalized:
+		return "finalized"
+	case TxEventDropped:
+		return "dropped"
+	case TxEventNotFound:
+		return "not-found"
+	}
+	return "unknown"
+}
+
+// TxEvent is a transition of a transaction watched by a TxWatcher
+type TxEvent struct {
+	Kind          TxEventKind
+	TransactionId string
+	FromGroup     int
+	ToGroup       int
+	// Status is the status of the transaction, Confirmed with its confirmations, MemPooled or TxNotFound
+	Status TxStatus
+}
+
+// Confirmations is a confirmation depth, reached when the transaction has at least
+// the given chain, from group and to group confirmations
+type Confirmations struct {
+	Chain     int
+	FromGroup int
+	ToGroup   int
+}
+
+// IsZero is true if no confirmation is required
+func (c Confirmations) IsZero() bool {
+	return c == Confirmations{}
+}
+
+// Reached is true if the transaction is confirmed with the confirmations
+func (c Confirmations) Reached(status TxStatus) bool {
+	confirmed, ok := status.(Confirmed)
+	return ok && confirmed.ChainConfirmations >= c.Chain &&
+		confirmed.FromGroupConfirmations >= c.FromGroup && confirmed.ToGroupConfirmations >= c.ToGroup
+}
+
+// TxWatcherConfig configures a TxWatcher
+type TxWatcherConfig struct {
+	// PollInterval is the interval between two polls, the poll interval of the client if 0
+	PollInterval time.Duration
+	// NotFoundTimeout is how long a transaction may stay unknown to the node, DefaultNotFoundTimeout if 0
+	NotFoundTimeout time.Duration
+	// Depth is the confirmation depth of the transactions watched without one, 1 chain confirmation if zero
+	Depth Confirmations
+	// Events, if set, receives the events of every transaction. The polling loop blocks until they are received.
+	Events chan<- TxEvent
+}
+
+// WatchOptions are the options of a watched transaction
+type WatchOptions struct {
+	// Depth is the confirmation depth of the transaction, the depth of the watcher if zero
+	Depth Confirmations
+	// OnEvent, if set, is called with the events of the transaction, from the polling loop
+	OnEvent func(TxEvent)
+}
+
+// TxWatcher tracks the status of many transactions with a single polling loop, see Run
+type TxWatcher struct {
+	client *Client
+	config TxWatcherConfig
+
+	mu  sync.Mutex
+	txs map[string]*watchedTx
+}
+
+type watchedTx struct {
+	fromGroup int
+	toGroup   int
+	opts      WatchOptions
+	// lost is since when the transaction is unknown to the node
+	lost   time.Time
+	seen   bool
+	status TxStatus
+}
+
+// NewTxWatcher creates a TxWatcher polling the node of the client
+func (a *Client) NewTxWatcher(config TxWatcherConfig) *TxWatcher {
+	if config.PollInterval <= 0 {
+		config.PollInterval = a.sleepTime
+	}
+	if config.NotFoundTimeout <= 0 {
+		config.NotFoundTimeout = DefaultNotFoundTimeout
+	}
+	if config.Depth.IsZero() {
+		config.Depth = Confirmations{Chain: 1}
+	}
+	return &TxWatcher{
+		client: a,
+		config: config,
+		txs:    make(map[string]*watchedTx),
+	}
+}
+
+// Watch starts watching a transaction, until it is finalized or not found.
+// Watching a transaction already watched replaces its options.
+func (w *TxWatcher) Watch(transactionId string, fromGroup int, toGroup int, opts WatchOptions) {
+	if opts.Depth.IsZero() {
+		opts.Depth = w.config.Depth
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if tx, ok := w.txs[transactionId]; ok {
+		tx.opts = opts
+		return
+	}
+	w.txs[transactionId] = &watchedTx{
+		fromGroup: fromGroup,
+		toGroup:   toGroup,
+		opts:      opts,
+		lost:      time.Now(),
+		status:    TxNotFound{},
+	}
+}
+
+// Unwatch stops watching a transaction, without any event
+func (w *TxWatcher) Unwatch(transactionId string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.txs, transactionId)
+}
+
+// Watching returns the number of transactions watched
+func (w *TxWatcher) Watching() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.txs)
+}
+
+// Run polls the status of the watched transactions until the context is done, returning its error.
+// Failing polls are logged and retried at the next interval.
+func (w *TxWatcher) Run(ctx context.Context) error {
+	for {
+		if err := w.Poll(ctx); err != nil {
+			return err
+		}
+		if err := sleep(ctx, w.config.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// Poll polls the status of the watched transactions once and fires their events.
+// Only the error of the context is returned.
+func (w *TxWatcher) Poll(ctx context.Context) error {
+	w.mu.Lock()
+	ids := make([]string, 0, len(w.txs))
+	for id := range w.txs {
+		ids = append(ids, id)
+	}
+	w.mu.Unlock()
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		w.mu.Lock()
+		tx, ok := w.txs[id]
+		var fromGroup, toGroup int
+		if ok {
+			fromGroup, toGroup = tx.fromGroup, tx.toGroup
+		}
+		w.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		status, err := w.client.GetTxStatusCtx(ctx, id, fromGroup, toGroup)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			w.client.log.Warnf("Failed to get the status of tx %s: %v", id, err)
+			continue
+		}
+		if err != nil {
+			status = TxNotFound{}
+		}
+		if err := w.update(ctx, id, status, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update records the new status of the transaction and fires the events of the transition
+func (w *TxWatcher) update(ctx context.Context, id string, status TxStatus, now time.Time) error {
+	w.mu.Lock()
+	tx, ok := w.txs[id]
+	if !ok {
+		w.mu.Unlock()
+		return nil
+	}
+	var kinds []TxEventKind
+	switch status.(type) {
+	case Confirmed:
+		// confirmations, or the block after a reorg, changed
+		if tx.status != status {
+			kinds = append(kinds, TxEventConfirmed)
+		}
+		if tx.opts.Depth.Reached(status) {
+			kinds = append(kinds, TxEventFinalized)
+			delete(w.txs, id)
+		}
+		tx.seen = true
+	case MemPooled:
+		if _, ok := tx.status.(MemPooled); !ok {
+			kinds = append(kinds, TxEventMemPooled)
+		}
+		tx.seen = true
+	default:
+		if tx.seen {
+			kinds = append(kinds, TxEventDropped)
+			tx.seen = false
+			tx.lost = now
+		} else if now.Sub(tx.lost) >= w.config.NotFoundTimeout {
+			kinds = append(kinds, TxEventNotFound)
+			delete(w.txs, id)
+		}
+	}
+	tx.status = status
+	onEvent := tx.opts.OnEvent
+	w.mu.Unlock()
+
+	for _, kind := range kinds {
+		event := TxEvent{Kind: kind, TransactionId: id, FromGroup: tx.fromGroup, ToGroup: tx.toGroup, Status: status}
+		w.client.log.Debugf("Tx %s %s", id, kind)
+		if onEvent != nil {
+			onEvent(event)
+		}
+		if w.config.Events != nil {
+			select {
+			case w.config.Events <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package alephium
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTxWatcher(t *testing.T) {
+
+	var mu sync.Mutex
+	statuses := map[string]string{
+		"a": `{"type":"MemPooled"}`,
+		"b": `{"type":"mem-pooled"}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		status, ok := statuses[r.URL.Query().Get("txId")]
+		if !ok {
+			_, _ = w.Write([]byte(`{"type":"TxNotFound"}`))
+			return
+		}
+		_, _ = w.Write([]byte(status))
+	}))
+	defer ts.Close()
+	setStatus := func(txId string, status string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if status == "" {
+			delete(statuses, txId)
+		} else {
+			statuses[txId] = status
+		}
+	}
+
+	alephiumClient, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+	events := make(chan TxEvent, 100)
+	watcher := alephiumClient.NewTxWatcher(TxWatcherConfig{NotFoundTimeout: 50 * time.Millisecond, Events: events})
+	var aEvents []TxEventKind
+	watcher.Watch("a", 0, 1, WatchOptions{
+		Depth:   Confirmations{Chain: 2},
+		OnEvent: func(event TxEvent) { aEvents = append(aEvents, event.Kind) },
+	})
+	watcher.Watch("b", 1, 1, WatchOptions{})
+	watcher.Watch("c", 2, 2, WatchOptions{})
+	assert.Equal(t, 3, watcher.Watching())
+
+	ctx := context.Background()
+	assert.Nil(t, watcher.Poll(ctx))
+	// no new event while the status doesn't change
+	assert.Nil(t, watcher.Poll(ctx))
+	setStatus("a", `{"type":"Confirmed","blockHash":"h","chainConfirmations":1,"fromGroupConfirmations":1,"toGroupConfirmations":1}`)
+	setStatus("b", "")
+	// c is never found
+	time.Sleep(60 * time.Millisecond)
+	assert.Nil(t, watcher.Poll(ctx))
+	assert.Equal(t, 2, watcher.Watching())
+	setStatus("a", `{"type":"Confirmed","blockHash":"h","chainConfirmations":2,"fromGroupConfirmations":2,"toGroupConfirmations":1}`)
+	assert.Nil(t, watcher.Poll(ctx))
+	assert.Equal(t, []TxEventKind{TxEventMemPooled, TxEventConfirmed, TxEventConfirmed, TxEventFinalized}, aEvents)
+	// b was dropped less than the timeout ago
+	assert.Equal(t, 1, watcher.Watching())
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Nil(t, watcher.Poll(ctx))
+	assert.Equal(t, 0, watcher.Watching())
+
+	close(events)
+	byTx := make(map[string][]TxEventKind)
+	for event := range events {
+		byTx[event.TransactionId] = append(byTx[event.TransactionId], event.Kind)
+	}
+	assert.Equal(t, aEvents, byTx["a"])
+	assert.Equal(t, []TxEventKind{TxEventMemPooled, TxEventDropped, TxEventNotFound}, byTx["b"])
+	assert.Equal(t, []TxEventKind{TxEventNotFound}, byTx["c"])
+}
+
+func TestTxWatcherRun(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"type":"confirmed","chainConfirmations":1,"fromGroupConfirmations":1,"toGroupConfirmations":1}`))
+	}))
+	defer ts.Close()
+
+	alephiumClient, err := NewClient(ts.URL, WithPollInterval(time.Hour))
+	assert.Nil(t, err)
+	events := make(chan TxEvent, 10)
+	watcher := alephiumClient.NewTxWatcher(TxWatcherConfig{Events: events})
+	watcher.Watch("tx", 0, 0, WatchOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- watcher.Run(ctx)
+	}()
+	assert.Equal(t, TxEventConfirmed, (<-events).Kind)
+	assert.Equal(t, TxEventFinalized, (<-events).Kind)
+	// the hour long poll interval is interrupted
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
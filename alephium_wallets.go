@@ -1,17 +1,22 @@
 package alephium
 
 import (
-	"strings"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
 )
 
 // GetWallets returns the list of wallet present on the full node
 func (a *Client) GetWallets() ([]WalletInfo, error) {
-	var wallets []WalletInfo
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("wallets").
-		Receive(&wallets, &errorDetail)
+	return a.GetWalletsCtx(context.Background())
+}
 
-	return wallets, relevantError(err, errorDetail)
+// GetWalletsCtx is like GetWallets, with a context
+func (a *Client) GetWalletsCtx(ctx context.Context) ([]WalletInfo, error) {
+	var wallets []WalletInfo
+	err := a.receive(ctx, a.slingClient.New().Path("wallets"), &wallets)
+	return wallets, err
 }
 
 type CreateWalletRequestBody struct {
@@ -24,6 +29,11 @@ type CreateWalletRequestBody struct {
 
 // CreateWallet creates a new wallet, generating mnemonic while doing so
 func (a *Client) CreateWallet(walletName string, password string, isMiner bool, mnemonicPassphrase string) (WalletCreate, error) {
+	return a.CreateWalletCtx(context.Background(), walletName, password, isMiner, mnemonicPassphrase)
+}
+
+// CreateWalletCtx is like CreateWallet, with a context
+func (a *Client) CreateWalletCtx(ctx context.Context, walletName string, password string, isMiner bool, mnemonicPassphrase string) (WalletCreate, error) {
 
 	body := CreateWalletRequestBody{
 		Password:           password,
@@ -33,11 +43,9 @@ func (a *Client) CreateWallet(walletName string, password string, isMiner bool,
 	}
 
 	var wallet WalletCreate
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Post("wallets").
-		BodyJSON(body).Receive(&wallet, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Post("wallets").BodyJSON(body), &wallet)
 
-	return wallet, relevantError(err, errorDetail)
+	return wallet, err
 }
 
 type RestoreWalletRequestBody struct {
@@ -51,6 +59,12 @@ type RestoreWalletRequestBody struct {
 // RestoreWallet creates a wallet with provided mnemonics (unlike CreateWallet which generates new mnemonics)
 func (a *Client) RestoreWallet(password string, mnemonic string, walletName string,
 	isMiner bool, mnemonicPassphrase string) (Wallet, error) {
+	return a.RestoreWalletCtx(context.Background(), password, mnemonic, walletName, isMiner, mnemonicPassphrase)
+}
+
+// RestoreWalletCtx is like RestoreWallet, with a context
+func (a *Client) RestoreWalletCtx(ctx context.Context, password string, mnemonic string, walletName string,
+	isMiner bool, mnemonicPassphrase string) (Wallet, error) {
 
 	body := RestoreWalletRequestBody{
 		Password:           password,
@@ -61,30 +75,34 @@ func (a *Client) RestoreWallet(password string, mnemonic string, walletName stri
 	}
 
 	var wallet Wallet
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Put("wallets").
-		BodyJSON(body).Receive(&wallet, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Put("wallets").BodyJSON(body), &wallet)
 
-	return wallet, relevantError(err, errorDetail)
+	return wallet, err
 }
 
 // GetWalletStatus returns the status of a given wallet
 func (a *Client) GetWalletStatus(walletName string) (WalletInfo, error) {
+	return a.GetWalletStatusCtx(context.Background(), walletName)
+}
+
+// GetWalletStatusCtx is like GetWalletStatus, with a context
+func (a *Client) GetWalletStatusCtx(ctx context.Context, walletName string) (WalletInfo, error) {
 	var walletInfo WalletInfo
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("wallets/"+walletName).
-		Receive(&walletInfo, &errorDetail)
-	return walletInfo, relevantError(err, errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("wallets/"+walletName), &walletInfo)
+	return walletInfo, err
 }
 
 // LockWallet locks a given wallet. Returns false if the wallet was already locked.
 func (a *Client) LockWallet(walletName string) (bool, error) {
+	return a.LockWalletCtx(context.Background(), walletName)
+}
+
+// LockWalletCtx is like LockWallet, with a context
+func (a *Client) LockWalletCtx(ctx context.Context, walletName string) (bool, error) {
 
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Post("wallets/"+walletName+"/lock").
-		Receive(nil, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Post("wallets/"+walletName+"/lock"), nil)
 
-	return true, relevantError(err, errorDetail)
+	return true, err
 }
 
 type WalletPasswordRequestBody struct {
@@ -95,50 +113,62 @@ type WalletPasswordRequestBody struct {
 // UnlockWallet unlocks wallet with the provided password and optional passphrase.
 // Returns true if the wallet got successfully unlocked, false when the wallet was already unlocked
 func (a *Client) UnlockWallet(walletName string, password string, mnemonicPassphrase string) (bool, error) {
+	return a.UnlockWalletCtx(context.Background(), walletName, password, mnemonicPassphrase)
+}
+
+// UnlockWalletCtx is like UnlockWallet, with a context
+func (a *Client) UnlockWalletCtx(ctx context.Context, walletName string, password string, mnemonicPassphrase string) (bool, error) {
 
 	body := WalletPasswordRequestBody{
 		Password:           password,
 		MnemonicPassphrase: mnemonicPassphrase,
 	}
 
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Post("wallets/"+walletName+"/unlock").
-		BodyJSON(body).Receive(nil, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Post("wallets/"+walletName+"/unlock").BodyJSON(body), nil)
 
-	return true, relevantError(err, errorDetail)
+	return true, err
 }
 
 // GetWalletBalances returns the balance of all the addresses inside the wallet.
 func (a *Client) GetWalletBalances(walletName string) (WalletBalances, error) {
+	return a.GetWalletBalancesCtx(context.Background(), walletName)
+}
+
+// GetWalletBalancesCtx is like GetWalletBalances, with a context
+func (a *Client) GetWalletBalancesCtx(ctx context.Context, walletName string) (WalletBalances, error) {
 
 	var walletBalances WalletBalances
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("wallets/"+walletName+"/balances").
-		Receive(&walletBalances, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("wallets/"+walletName+"/balances"), &walletBalances)
 
-	return walletBalances, relevantError(err, errorDetail)
+	return walletBalances, err
 }
 
 // GetWalletAddresses lists all the addresses from a wallet
 func (a *Client) GetWalletAddresses(walletName string) (WalletAddresses, error) {
+	return a.GetWalletAddressesCtx(context.Background(), walletName)
+}
+
+// GetWalletAddressesCtx is like GetWalletAddresses, with a context
+func (a *Client) GetWalletAddressesCtx(ctx context.Context, walletName string) (WalletAddresses, error) {
 
 	var walletAddresses WalletAddresses
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("wallets/"+walletName+"/addresses").
-		Receive(&walletAddresses, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("wallets/"+walletName+"/addresses"), &walletAddresses)
 
-	return walletAddresses, relevantError(err, errorDetail)
+	return walletAddresses, err
 }
 
 // GetWalletAddressDetail returns detailed info about a specific address of a wallet
 func (a *Client) GetWalletAddressDetail(walletName string, address string) (AddressDetailResponse, error) {
+	return a.GetWalletAddressDetailCtx(context.Background(), walletName, address)
+}
+
+// GetWalletAddressDetailCtx is like GetWalletAddressDetail, with a context
+func (a *Client) GetWalletAddressDetailCtx(ctx context.Context, walletName string, address string) (AddressDetailResponse, error) {
 
 	var addressDetailResponse AddressDetailResponse
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("wallets/"+walletName+"/addresses/"+address).
-		Receive(&addressDetailResponse, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("wallets/"+walletName+"/addresses/"+address), &addressDetailResponse)
 
-	return addressDetailResponse, relevantError(err, errorDetail)
+	return addressDetailResponse, err
 }
 
 type AddressDetailResponse struct {
@@ -149,13 +179,32 @@ type AddressDetailResponse struct {
 
 type TransferRequest struct {
 	Destinations []TransferDestination `json:"destinations"`
+	Gas          int                   `json:"gas,omitempty"`
+	GasPrice     *ALPH                 `json:"gasPrice,omitempty"`
 }
 
 type TransferDestination struct {
-	Address string `json:"address"`
-	Amount  ALPH   `json:"amount"`
+	Address string          `json:"address"`
+	Amount  ALPH            `json:"amount"`
+	Tokens  []TransferToken `json:"tokens,omitempty"`
+	// LockTime is the timestamp, in milliseconds, until which the output can't be spent, 0 if not locked
+	LockTime int64 `json:"lockTime,omitempty"`
+}
+
+// Validate checks the address, the tokens and the lock time of the destination, without calling the node
+func (d TransferDestination) Validate() error {
+	tokens := make([]Token, 0, len(d.Tokens))
+	for _, token := range d.Tokens {
+		amount, ok := new(big.Int).SetString(token.Amount, 10)
+		if !ok {
+			return fmt.Errorf("invalid amount %q of token %s to %s", token.Amount, token.Id, d.Address)
+		}
+		tokens = append(tokens, Token{Id: token.Id, Amount: U256{Value: amount}})
+	}
+	return validateDestination(d.Address, tokens, d.LockTime)
 }
 
+// TransferToken is an amount of a token sent to a TransferDestination, the amount being a decimal string
 type TransferToken struct {
 	Id     string `json:"id"`
 	Amount string `json:"amount"`
@@ -163,16 +212,117 @@ type TransferToken struct {
 
 // Transfer transfers ALPH from one wallet to a given address
 func (a *Client) Transfer(walletName string, address string, amount ALPH) (Transaction, error) {
+	return a.TransferCtx(context.Background(), walletName, address, amount)
+}
+
+// TransferCtx is like Transfer, with a context
+func (a *Client) TransferCtx(ctx context.Context, walletName string, address string, amount ALPH) (Transaction, error) {
+	return a.TransferWithOptionsCtx(ctx, walletName, TransferDestination{Address: address, Amount: amount}, TransactionOptions{})
+}
+
+// TransferWithOptions transfers ALPH, and tokens, from one wallet to a destination, possibly locked,
+// with the gas amount and price of the transaction
+func (a *Client) TransferWithOptions(walletName string, destination TransferDestination, opts TransactionOptions) (Transaction, error) {
+	return a.TransferWithOptionsCtx(context.Background(), walletName, destination, opts)
+}
+
+// TransferWithOptionsCtx is like TransferWithOptions, with a context
+func (a *Client) TransferWithOptionsCtx(ctx context.Context, walletName string, destination TransferDestination,
+	opts TransactionOptions) (Transaction, error) {
 
-	// TODO: run sanity check on address and amount
-	body := TransferRequest{Destinations: []TransferDestination{{Address: address, Amount: amount}}}
+	if err := destination.Validate(); err != nil {
+		return Transaction{}, err
+	}
+	if err := opts.Validate(); err != nil {
+		return Transaction{}, err
+	}
+	body := TransferRequest{
+		Destinations: []TransferDestination{destination},
+		Gas:          opts.GasAmount,
+		GasPrice:     opts.GasPrice,
+	}
 
 	var transaction Transaction
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Post("wallets/"+walletName+"/transfer").
-		BodyJSON(body).Receive(&transaction, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Post("wallets/"+walletName+"/transfer").BodyJSON(body), &transaction)
+
+	return transaction, err
+}
+
+const (
+	// DefaultTransferDestinations is the number of destinations paid by a transaction of TransferMany
+	DefaultTransferDestinations = 20
+)
 
-	return transaction, relevantError(err, errorDetail)
+// TransferManyOptions configures TransferMany
+type TransferManyOptions struct {
+	// TransactionOptions are the gas settings of every transaction
+	TransactionOptions
+	// MaxDestinations is the number of destinations paid by a transaction, DefaultTransferDestinations if 0
+	MaxDestinations int
+}
+
+// TransferMany transfers ALPH, and tokens, from one wallet to any number of destinations, with as many
+// transactions of at most MaxDestinations destinations as needed, submitted one after the other.
+// The transactions submitted are returned in order. If some of them failed, the other ones are still
+// submitted and a *TransferManyError reports the destinations not paid, with the error of their transaction.
+// The destinations of a transaction which failed after being sent, without being rejected by the node,
+// are flagged Uncertain: they must not be retried blindly.
+func (a *Client) TransferMany(walletName string, destinations []TransferDestination, opts TransferManyOptions) ([]Transaction, error) {
+	return a.TransferManyCtx(context.Background(), walletName, destinations, opts)
+}
+
+// TransferManyCtx is like TransferMany, with a context. Once the context is done, the destinations
+// left are reported as failed with the error of the context.
+func (a *Client) TransferManyCtx(ctx context.Context, walletName string, destinations []TransferDestination,
+	opts TransferManyOptions) ([]Transaction, error) {
+
+	for _, destination := range destinations {
+		if err := destination.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.MaxDestinations <= 0 {
+		opts.MaxDestinations = DefaultTransferDestinations
+	}
+
+	transactions := make([]Transaction, 0, (len(destinations)+opts.MaxDestinations-1)/opts.MaxDestinations)
+	var failures []TransferFailure
+	for start := 0; start < len(destinations); start += opts.MaxDestinations {
+		end := start + opts.MaxDestinations
+		if end > len(destinations) {
+			end = len(destinations)
+		}
+		err := ctx.Err()
+		uncertain := false
+		if err == nil {
+			body := TransferRequest{
+				Destinations: destinations[start:end],
+				Gas:          opts.GasAmount,
+				GasPrice:     opts.GasPrice,
+			}
+			var transaction Transaction
+			err = a.receive(ctx, a.slingClient.New().Post("wallets/"+walletName+"/transfer").BodyJSON(body), &transaction)
+			if err == nil {
+				a.log.Debugf("Transferred to destinations %d to %d of %d with tx %s", start, end-1, len(destinations), transaction.TransactionId)
+				transactions = append(transactions, transaction)
+				continue
+			}
+			a.log.Warnf("Failed to transfer to destinations %d to %d of %d: %v", start, end-1, len(destinations), err)
+			var apiErr *APIError
+			uncertain = !errors.As(err, &apiErr) || apiErr.StatusCode >= 500
+		}
+		for i := start; i < end; i++ {
+			failures = append(failures, TransferFailure{Index: i, Destination: destinations[i], Err: err, Uncertain: uncertain})
+		}
+	}
+
+	if len(failures) > 0 {
+		return transactions, &TransferManyError{Failures: failures}
+	}
+	return transactions, nil
 }
 
 type SweepAllRequest struct {
@@ -181,16 +331,21 @@ type SweepAllRequest struct {
 
 // SweepAll transfers all (unlocked) ALPH from a wallet to another address
 func (a *Client) SweepAll(walletName string, toAddress string) (Transaction, error) {
+	return a.SweepAllCtx(context.Background(), walletName, toAddress)
+}
+
+// SweepAllCtx is like SweepAll, with a context
+func (a *Client) SweepAllCtx(ctx context.Context, walletName string, toAddress string) (Transaction, error) {
 
-	// TODO: run sanity check on address
+	if err := validateAddress(toAddress); err != nil {
+		return Transaction{}, err
+	}
 	body := SweepAllRequest{Address: toAddress}
 
 	var transaction Transaction
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Post("wallets/"+walletName+"/sweep-all").
-		BodyJSON(body).Receive(&transaction, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Post("wallets/"+walletName+"/sweep-all").BodyJSON(body), &transaction)
 
-	return transaction, relevantError(err, errorDetail)
+	return transaction, err
 }
 
 type RevealMnemonicRequest struct {
@@ -203,15 +358,18 @@ type RevealMnemonicResponse struct {
 
 // RevealWalletMnemonic reveals your mnemonic. Please use with caution!!
 func (a *Client) RevealWalletMnemonic(walletName string, password string) (string, error) {
+	return a.RevealWalletMnemonicCtx(context.Background(), walletName, password)
+}
+
+// RevealWalletMnemonicCtx is like RevealWalletMnemonic, with a context
+func (a *Client) RevealWalletMnemonicCtx(ctx context.Context, walletName string, password string) (string, error) {
 
 	body := RevealMnemonicRequest{Password: password}
 
 	var response RevealMnemonicResponse
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Get("wallets/"+walletName+"/reveal-mnemonic").
-		BodyJSON(body).Receive(&response, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Get("wallets/"+walletName+"/reveal-mnemonic").BodyJSON(body), &response)
 
-	return response.Mnemonic, relevantError(err, errorDetail)
+	return response.Mnemonic, err
 }
 
 type SignRequest struct {
@@ -224,25 +382,31 @@ type SignResponse struct {
 
 // Sign signs the given data and returns the signature
 func (a *Client) Sign(walletName string, data string) (string, error) {
+	return a.SignCtx(context.Background(), walletName, data)
+}
+
+// SignCtx is like Sign, with a context
+func (a *Client) SignCtx(ctx context.Context, walletName string, data string) (string, error) {
 
 	body := SignRequest{Data: data}
 
 	var response SignResponse
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Post("wallets/"+walletName+"/sign").
-		BodyJSON(body).Receive(&response, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Post("wallets/"+walletName+"/sign").BodyJSON(body), &response)
 
-	return response.Signature, relevantError(err, errorDetail)
+	return response.Signature, err
 }
 
 // DeriveNextAddress derives the next address
 func (a *Client) DeriveNextAddress(walletName string) (Address, error) {
+	return a.DeriveNextAddressCtx(context.Background(), walletName)
+}
+
+// DeriveNextAddressCtx is like DeriveNextAddress, with a context
+func (a *Client) DeriveNextAddressCtx(ctx context.Context, walletName string) (Address, error) {
 	var address Address
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Post("wallets/"+walletName+"/derive-next-address").
-		Receive(&address, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Post("wallets/"+walletName+"/derive-next-address"), &address)
 
-	return address, relevantError(err, errorDetail)
+	return address, err
 }
 
 type AddressBodyRequest struct {
@@ -251,36 +415,45 @@ type AddressBodyRequest struct {
 
 // ChangeActiveAddress changes the active address of the wallet. Has no effect on non-miner wallet.
 func (a *Client) ChangeActiveAddress(walletName string, activeAddress string) (bool, error) {
+	return a.ChangeActiveAddressCtx(context.Background(), walletName, activeAddress)
+}
+
+// ChangeActiveAddressCtx is like ChangeActiveAddress, with a context
+func (a *Client) ChangeActiveAddressCtx(ctx context.Context, walletName string, activeAddress string) (bool, error) {
 
 	body := AddressBodyRequest{Address: activeAddress}
 
-	//var address string
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Post("wallets/"+walletName+"/change-active-address").
-		BodyJSON(body).Receive(nil, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Post("wallets/"+walletName+"/change-active-address").BodyJSON(body), nil)
 
-	return true, relevantError(err, errorDetail)
+	return true, err
 }
 
 // DeleteWallet deletes a wallet.
 func (a *Client) DeleteWallet(walletName string, walletPassword string) (bool, error) {
+	return a.DeleteWalletCtx(context.Background(), walletName, walletPassword)
+}
+
+// DeleteWalletCtx is like DeleteWallet, with a context
+func (a *Client) DeleteWalletCtx(ctx context.Context, walletName string, walletPassword string) (bool, error) {
 
 	body := WalletPasswordRequestBody{Password: walletPassword}
 
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Delete("wallets/"+walletName).
-		BodyJSON(body).Receive(nil, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Delete("wallets/"+walletName).BodyJSON(body), nil)
 
-	return true, relevantError(err, errorDetail)
+	return true, err
 }
 
 // CheckWalletExist is a convenience function which checks if the wallet exists,
-// since this information is based on the error string returned by the API call.
-// TODO: should the "not found" exception being typed?
+// i.e. the node doesn't answer ErrNotFound for its status.
 func (a *Client) CheckWalletExist(walletName string) (bool, error) {
-	_, err := a.GetWalletStatus(walletName)
+	return a.CheckWalletExistCtx(context.Background(), walletName)
+}
+
+// CheckWalletExistCtx is like CheckWalletExist, with a context
+func (a *Client) CheckWalletExistCtx(ctx context.Context, walletName string) (bool, error) {
+	_, err := a.GetWalletStatusCtx(ctx, walletName)
 	if err != nil {
-		if strings.HasPrefix(walletName+" not found", err.Error()) {
+		if errors.Is(err, ErrNotFound) {
 			return false, nil
 		}
 		return false, err
@@ -302,21 +475,27 @@ type MinerWalletAddresses struct {
 
 // GetMinerWalletAddresses lists all the addresses from a miner wallet
 func (a *Client) GetMinerWalletAddresses(walletName string) ([]MinerWalletAddresses, error) {
+	return a.GetMinerWalletAddressesCtx(context.Background(), walletName)
+}
+
+// GetMinerWalletAddressesCtx is like GetMinerWalletAddresses, with a context
+func (a *Client) GetMinerWalletAddressesCtx(ctx context.Context, walletName string) ([]MinerWalletAddresses, error) {
 
 	var minerAddresses []MinerWalletAddresses
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Path("wallets/"+walletName+"/miner-addresses").
-		Receive(&minerAddresses, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Path("wallets/"+walletName+"/miner-addresses"), &minerAddresses)
 
-	return minerAddresses, relevantError(err, errorDetail)
+	return minerAddresses, err
 }
 
 // DeriveNextMinerAddresses derives the next miner address
 func (a *Client) DeriveNextMinerAddresses(walletName string) ([]WalletAddress, error) {
+	return a.DeriveNextMinerAddressesCtx(context.Background(), walletName)
+}
+
+// DeriveNextMinerAddressesCtx is like DeriveNextMinerAddresses, with a context
+func (a *Client) DeriveNextMinerAddressesCtx(ctx context.Context, walletName string) ([]WalletAddress, error) {
 	var addresses []WalletAddress
-	var errorDetail ErrorDetail
-	_, err := a.slingClient.New().Post("wallets/"+walletName+"/derive-next-miner-addresses").
-		Receive(&addresses, &errorDetail)
+	err := a.receive(ctx, a.slingClient.New().Post("wallets/"+walletName+"/derive-next-miner-addresses"), &addresses)
 
-	return addresses, relevantError(err, errorDetail)
+	return addresses, err
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/docker/go-connections/nat"
 	"github.com/sqooba/go-common/logging"
@@ -11,6 +12,8 @@ import (
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -239,3 +242,63 @@ func TestJSONALF(t *testing.T) {
 	assert.Nil(t, err)
 	fmt.Printf("json: %s\n", string(b))
 }
+
+func TestTransferMany(t *testing.T) {
+
+	var batches []int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body TransferRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		batches = append(batches, len(body.Destinations))
+		w.Header().Set("Content-Type", "application/json")
+		if len(batches) == 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if len(batches) == 4 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"detail":"not enough balance"}`))
+			return
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"txId":"tx%d","fromGroup":0,"toGroup":0}`, len(batches))))
+	}))
+	defer ts.Close()
+
+	alephiumClient, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+	amount, _ := ALPHFromALPHString("1")
+	destinations := make([]TransferDestination, 45)
+	for i := range destinations {
+		destinations[i] = TransferDestination{Address: "1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi", Amount: amount}
+	}
+
+	transactions, err := alephiumClient.TransferMany("wallet", destinations, TransferManyOptions{})
+	assert.Equal(t, []int{20, 20, 5}, batches)
+	assert.Equal(t, 2, len(transactions))
+	assert.Equal(t, "tx1", transactions[0].TransactionId)
+	assert.Equal(t, "tx3", transactions[1].TransactionId)
+	assert.True(t, errors.Is(err, ErrServiceUnavailable))
+	var transferManyError *TransferManyError
+	assert.True(t, errors.As(err, &transferManyError))
+	assert.Equal(t, 20, len(transferManyError.Failures))
+	assert.Equal(t, 20, transferManyError.Failures[0].Index)
+	assert.Equal(t, 39, transferManyError.Failures[19].Index)
+	// the node may have submitted the transaction anyway
+	assert.True(t, transferManyError.Failures[0].Uncertain)
+
+	// nothing is transferred once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	transactions, err = alephiumClient.TransferManyCtx(ctx, "wallet", destinations, TransferManyOptions{MaxDestinations: 10})
+	assert.Equal(t, 0, len(transactions))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, errors.As(err, &transferManyError))
+	assert.Equal(t, 45, len(transferManyError.Failures))
+	assert.False(t, transferManyError.Failures[0].Uncertain)
+	assert.Equal(t, 3, len(batches))
+
+	// the node rejected the transaction
+	_, err = alephiumClient.TransferMany("wallet", destinations[:5], TransferManyOptions{})
+	assert.True(t, errors.As(err, &transferManyError))
+	assert.False(t, transferManyError.Failures[0].Uncertain)
+}
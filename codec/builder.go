@@ -0,0 +1,53 @@
+package codec
+
+import (
+	"github.com/touilleio/alephium-go-client/address"
+	"math/big"
+)
+
+// Gas schedule of the node, used to estimate the gas of transactions built locally
+const (
+	MinimalGas      = int32(20000)
+	MaximalGasPerTx = int32(625000)
+	TxBaseGas       = int32(1000)
+	TxInputBaseGas  = int32(2000)
+	TxOutputBaseGas = int32(4500)
+	P2PKHUnlockGas  = int32(2060)
+)
+
+// DefaultGasPrice is the default gas price of the node, in attoALPH
+var DefaultGasPrice = big.NewInt(100000000000)
+
+// DustUtxoAmount is the minimal amount of an output accepted by the node, in attoALPH
+var DustUtxoAmount = big.NewInt(1000000000000000)
+
+// EstimateP2PKHGas estimates the gas of a transaction spending P2PKH inputs
+func EstimateP2PKHGas(inputs int, outputs int) int32 {
+	gas := TxBaseGas + int32(inputs)*(TxInputBaseGas+P2PKHUnlockGas) + int32(outputs)*TxOutputBaseGas
+	if gas < MinimalGas {
+		return MinimalGas
+	}
+	return gas
+}
+
+// GasFee is the fee paid for the gas amount at the gas price
+func GasFee(gasAmount int32, gasPrice *big.Int) *big.Int {
+	return new(big.Int).Mul(big.NewInt(int64(gasAmount)), gasPrice)
+}
+
+// NewP2PKHInput creates an input spending the output referenced by hint and key, unlocked by the public key
+func NewP2PKHInput(hint uint32, key []byte, publicKey []byte) Input {
+	return Input{
+		Hint: hint,
+		Key:  key,
+		UnlockScript: UnlockScript{
+			Type:       UnlockP2PKH,
+			PublicKeys: []IndexedPublicKey{{PublicKey: publicKey}},
+		},
+	}
+}
+
+// NewOutput creates an output of the amount to the address, without tokens nor lock time
+func NewOutput(amount *big.Int, to address.Address) Output {
+	return Output{Amount: amount, Address: to}
+}
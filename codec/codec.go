@@ -0,0 +1,359 @@
+// Package codec decodes and encodes the binary serialization of unsigned transactions, as found in
+// UnsignedTransaction.UnsignedTx, so that a transaction built by the node can be checked before
+// being signed.
+//
+// Transactions running a script are only partly supported: the script is not decoded, it is kept as raw
+// bytes, its end being guessed as the position where the rest of the transaction decodes. That search is
+// quadratic in the size of the transaction, and fails with ErrAmbiguousScript when several positions decode,
+// so some valid transactions with a script can't be decoded. Inputs unlocked by a P2SH script are not
+// supported, ErrScriptUnsupported is returned instead.
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/serde"
+	"golang.org/x/crypto/blake2b"
+	"math/big"
+)
+
+const (
+	HashLength      = 32
+	PublicKeyLength = 33
+)
+
+var (
+	ErrScriptUnsupported = errors.New("P2SH unlock scripts are not supported")
+	ErrAmbiguousScript   = errors.New("the end of the script is ambiguous")
+	ErrTrailingBytes     = errors.New("trailing bytes after the transaction")
+)
+
+// UnlockScriptType is the type of an unlock script
+type UnlockScriptType byte
+
+const (
+	UnlockP2PKH  UnlockScriptType = 0x00
+	UnlockP2MPKH UnlockScriptType = 0x01
+	UnlockP2SH   UnlockScriptType = 0x02
+)
+
+// UnsignedTx is a decoded unsigned transaction
+type UnsignedTx struct {
+	Version   byte
+	NetworkId byte
+	// Script is the serialized script run by the transaction, nil if none. It is kept as is, not decoded,
+	// see the package documentation.
+	Script    []byte
+	GasAmount int32
+	GasPrice  *big.Int
+	Inputs    []Input
+	// FixedOutputs are the outputs of the transaction, change included
+	FixedOutputs []Output
+}
+
+// Input spends the output referenced by its hint and key
+type Input struct {
+	Hint         uint32
+	Key          []byte
+	UnlockScript UnlockScript
+}
+
+// UnlockScript proves the right to spend an output, with the public key of a P2PKH lockup script,
+// or the public keys and their index of a P2MPKH lockup script
+type UnlockScript struct {
+	Type       UnlockScriptType
+	PublicKeys []IndexedPublicKey
+}
+
+type IndexedPublicKey struct {
+	PublicKey []byte
+	Index     int32
+}
+
+// Output is a fixed output
+type Output struct {
+	Amount  *big.Int
+	Address address.Address
+	// LockTime is the timestamp, in milliseconds, until which the output can't be spent
+	LockTime       uint64
+	Tokens         []Token
+	AdditionalData []byte
+}
+
+type Token struct {
+	Id     []byte
+	Amount *big.Int
+}
+
+// DecodeUnsignedTxHex decodes a hex encoded unsigned transaction
+func DecodeUnsignedTxHex(s string) (UnsignedTx, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return UnsignedTx{}, err
+	}
+	return DecodeUnsignedTx(b)
+}
+
+// DecodeUnsignedTx decodes an unsigned transaction. Transactions with a script are only partly supported,
+// see the package documentation.
+func DecodeUnsignedTx(b []byte) (UnsignedTx, error) {
+	var tx UnsignedTx
+	if len(b) < 3 {
+		return tx, serde.ErrUnexpectedEnd
+	}
+	tx.Version, tx.NetworkId = b[0], b[1]
+	switch b[2] {
+	case 0:
+		err := tx.decodeFixedPart(b[3:])
+		return tx, err
+	case 1:
+	default:
+		return tx, fmt.Errorf("invalid script option %d", b[2])
+	}
+
+	// the script is not decoded: it ends where the rest of the transaction can be decoded, with a valid
+	// gas and at least an input, which must happen at a single position
+	found := false
+	for end := 4; end < len(b); end++ {
+		var candidate UnsignedTx
+		if candidate.decodeFixedPart(b[end:]) != nil || candidate.GasAmount < MinimalGas ||
+			candidate.GasAmount > MaximalGasPerTx || candidate.GasPrice.Sign() <= 0 || len(candidate.Inputs) == 0 {
+			continue
+		}
+		if found {
+			return UnsignedTx{}, ErrAmbiguousScript
+		}
+		found = true
+		candidate.Version, candidate.NetworkId, candidate.Script = tx.Version, tx.NetworkId, b[3:end]
+		tx = candidate
+	}
+	if !found {
+		return tx, fmt.Errorf("invalid transaction with a script: %w", serde.ErrUnexpectedEnd)
+	}
+	return tx, nil
+}
+
+// decodeFixedPart decodes the gas, the inputs and the outputs, which must end b
+func (tx *UnsignedTx) decodeFixedPart(rest []byte) error {
+	var err error
+	if tx.GasAmount, rest, err = serde.DecodeI32(rest); err != nil {
+		return fmt.Errorf("invalid gas amount: %w", err)
+	}
+	if tx.GasPrice, rest, err = serde.DecodeU256(rest); err != nil {
+		return fmt.Errorf("invalid gas price: %w", err)
+	}
+
+	// the slices grow with the decoded elements, the lengths are not trusted
+	n, rest, err := decodeLength(rest)
+	if err != nil {
+		return fmt.Errorf("invalid inputs: %w", err)
+	}
+	for i := 0; i < n; i++ {
+		var input Input
+		if input, rest, err = decodeInput(rest); err != nil {
+			return fmt.Errorf("invalid input %d: %w", i, err)
+		}
+		tx.Inputs = append(tx.Inputs, input)
+	}
+
+	n, rest, err = decodeLength(rest)
+	if err != nil {
+		return fmt.Errorf("invalid outputs: %w", err)
+	}
+	for i := 0; i < n; i++ {
+		var output Output
+		if output, rest, err = decodeOutput(rest); err != nil {
+			return fmt.Errorf("invalid output %d: %w", i, err)
+		}
+		tx.FixedOutputs = append(tx.FixedOutputs, output)
+	}
+
+	if len(rest) != 0 {
+		return ErrTrailingBytes
+	}
+	return nil
+}
+
+// Encode serializes the transaction
+func (tx UnsignedTx) Encode() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(tx.Version)
+	buf.WriteByte(tx.NetworkId)
+	if tx.Script == nil {
+		buf.WriteByte(0)
+	} else {
+		buf.WriteByte(1)
+		buf.Write(tx.Script)
+	}
+	buf.Write(serde.EncodeI32(tx.GasAmount))
+	buf.Write(serde.EncodeU256(tx.GasPrice))
+	buf.Write(serde.EncodeI32(int32(len(tx.Inputs))))
+	for _, input := range tx.Inputs {
+		input.encode(&buf)
+	}
+	buf.Write(serde.EncodeI32(int32(len(tx.FixedOutputs))))
+	for _, output := range tx.FixedOutputs {
+		output.encode(&buf)
+	}
+	return buf.Bytes()
+}
+
+// Hex serializes the transaction, hex encoded like UnsignedTransaction.UnsignedTx
+func (tx UnsignedTx) Hex() string {
+	return hex.EncodeToString(tx.Encode())
+}
+
+// Id computes the hex encoded id of the transaction, the hash to be signed
+func (tx UnsignedTx) Id() string {
+	hash := blake2b.Sum256(tx.Encode())
+	return hex.EncodeToString(hash[:])
+}
+
+func decodeInput(b []byte) (Input, []byte, error) {
+	var input Input
+	if len(b) < 4+HashLength+1 {
+		return input, nil, serde.ErrUnexpectedEnd
+	}
+	input.Hint = binary.BigEndian.Uint32(b)
+	input.Key = b[4 : 4+HashLength]
+	var err error
+	input.UnlockScript, b, err = decodeUnlockScript(b[4+HashLength:])
+	return input, b, err
+}
+
+// DecodeUnlockScriptHex decodes a hex encoded unlock script, as found in the inputs returned by the node
+func DecodeUnlockScriptHex(s string) (UnlockScript, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return UnlockScript{}, err
+	}
+	script, rest, err := decodeUnlockScript(b)
+	if err != nil {
+		return script, err
+	}
+	if len(rest) != 0 {
+		return script, ErrTrailingBytes
+	}
+	return script, nil
+}
+
+func decodeUnlockScript(b []byte) (UnlockScript, []byte, error) {
+	var script UnlockScript
+	if len(b) < 1 {
+		return script, nil, serde.ErrUnexpectedEnd
+	}
+	script.Type = UnlockScriptType(b[0])
+	rest := b[1:]
+
+	switch script.Type {
+	case UnlockP2PKH:
+		if len(rest) < PublicKeyLength {
+			return script, nil, serde.ErrUnexpectedEnd
+		}
+		script.PublicKeys = []IndexedPublicKey{{PublicKey: rest[:PublicKeyLength]}}
+		return script, rest[PublicKeyLength:], nil
+	case UnlockP2MPKH:
+		n, rest, err := decodeLength(rest)
+		if err != nil {
+			return script, nil, err
+		}
+		for i := 0; i < n; i++ {
+			if len(rest) < PublicKeyLength {
+				return script, nil, serde.ErrUnexpectedEnd
+			}
+			key := IndexedPublicKey{PublicKey: rest[:PublicKeyLength]}
+			if key.Index, rest, err = serde.DecodeI32(rest[PublicKeyLength:]); err != nil {
+				return script, nil, err
+			}
+			script.PublicKeys = append(script.PublicKeys, key)
+		}
+		return script, rest, nil
+	case UnlockP2SH:
+		return script, nil, ErrScriptUnsupported
+	default:
+		return script, nil, fmt.Errorf("unknown unlock script type %d", script.Type)
+	}
+}
+
+func (input Input) encode(buf *bytes.Buffer) {
+	hint := make([]byte, 4)
+	binary.BigEndian.PutUint32(hint, input.Hint)
+	buf.Write(hint)
+	buf.Write(input.Key)
+	buf.WriteByte(byte(input.UnlockScript.Type))
+	switch input.UnlockScript.Type {
+	case UnlockP2PKH:
+		buf.Write(input.UnlockScript.PublicKeys[0].PublicKey)
+	case UnlockP2MPKH:
+		buf.Write(serde.EncodeI32(int32(len(input.UnlockScript.PublicKeys))))
+		for _, key := range input.UnlockScript.PublicKeys {
+			buf.Write(key.PublicKey)
+			buf.Write(serde.EncodeI32(key.Index))
+		}
+	}
+}
+
+func decodeOutput(b []byte) (Output, []byte, error) {
+	var output Output
+	var err error
+	if output.Amount, b, err = serde.DecodeU256(b); err != nil {
+		return output, nil, err
+	}
+	if output.Address, b, err = address.DecodeLockupScript(b); err != nil {
+		return output, nil, err
+	}
+	if len(b) < 8 {
+		return output, nil, serde.ErrUnexpectedEnd
+	}
+	output.LockTime = binary.BigEndian.Uint64(b)
+	b = b[8:]
+
+	n, b, err := decodeLength(b)
+	if err != nil {
+		return output, nil, err
+	}
+	for i := 0; i < n; i++ {
+		if len(b) < HashLength {
+			return output, nil, serde.ErrUnexpectedEnd
+		}
+		token := Token{Id: b[:HashLength]}
+		if token.Amount, b, err = serde.DecodeU256(b[HashLength:]); err != nil {
+			return output, nil, err
+		}
+		output.Tokens = append(output.Tokens, token)
+	}
+
+	if output.AdditionalData, b, err = serde.DecodeBytes(b); err != nil {
+		return output, nil, err
+	}
+	return output, b, nil
+}
+
+func (output Output) encode(buf *bytes.Buffer) {
+	buf.Write(serde.EncodeU256(output.Amount))
+	buf.Write(output.Address.Bytes())
+	lockTime := make([]byte, 8)
+	binary.BigEndian.PutUint64(lockTime, output.LockTime)
+	buf.Write(lockTime)
+	buf.Write(serde.EncodeI32(int32(len(output.Tokens))))
+	for _, token := range output.Tokens {
+		buf.Write(token.Id)
+		buf.Write(serde.EncodeU256(token.Amount))
+	}
+	buf.Write(serde.EncodeBytes(output.AdditionalData))
+}
+
+func decodeLength(b []byte) (int, []byte, error) {
+	n, rest, err := serde.DecodeI32(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	if n < 0 {
+		return 0, nil, fmt.Errorf("negative length %d", n)
+	}
+	return int(n), rest, nil
+}
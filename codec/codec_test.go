@@ -0,0 +1,109 @@
+package codec
+
+import (
+	"encoding/hex"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/serde"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+var testTx = strings.Join([]string{
+	"00",           // version
+	"01",           // network id
+	"00",           // no script
+	"80004e20",     // gas amount 20000
+	"c1174876e800", // gas price 100000000000
+	"01",           // 1 input
+	"a1b2c3d4" + strings.Repeat("11", 32) + "00" + "02" + strings.Repeat("22", 32),
+	"02", // 2 outputs
+	"c40de0b6b3a7640000" + "00" + strings.Repeat("33", 32) + "0000000000000000" + "00" + "00",
+	"05" + "00" + strings.Repeat("44", 32) + "000001792f864800" + "01" + strings.Repeat("55", 32) + "0a" + "02abcd",
+}, "")
+
+func TestDecodeUnsignedTx(t *testing.T) {
+
+	tx, err := DecodeUnsignedTxHex(testTx)
+	assert.Nil(t, err)
+	assert.Equal(t, byte(0), tx.Version)
+	assert.Equal(t, byte(1), tx.NetworkId)
+	assert.Equal(t, int32(20000), tx.GasAmount)
+	assert.Equal(t, "100000000000", tx.GasPrice.String())
+
+	assert.Equal(t, 1, len(tx.Inputs))
+	assert.Equal(t, uint32(0xa1b2c3d4), tx.Inputs[0].Hint)
+	assert.Equal(t, strings.Repeat("11", 32), hex.EncodeToString(tx.Inputs[0].Key))
+	assert.Equal(t, UnlockP2PKH, tx.Inputs[0].UnlockScript.Type)
+	assert.Equal(t, "02"+strings.Repeat("22", 32), hex.EncodeToString(tx.Inputs[0].UnlockScript.PublicKeys[0].PublicKey))
+
+	assert.Equal(t, 2, len(tx.FixedOutputs))
+	assert.Equal(t, "1000000000000000000", tx.FixedOutputs[0].Amount.String())
+	assert.Equal(t, address.P2PKH, tx.FixedOutputs[0].Address.Type())
+	assert.Equal(t, uint64(0), tx.FixedOutputs[0].LockTime)
+	assert.Equal(t, 0, len(tx.FixedOutputs[0].Tokens))
+	assert.Equal(t, uint64(1620000000000), tx.FixedOutputs[1].LockTime)
+	assert.Equal(t, 1, len(tx.FixedOutputs[1].Tokens))
+	assert.Equal(t, strings.Repeat("55", 32), hex.EncodeToString(tx.FixedOutputs[1].Tokens[0].Id))
+	assert.Equal(t, "10", tx.FixedOutputs[1].Tokens[0].Amount.String())
+	assert.Equal(t, []byte{0xab, 0xcd}, tx.FixedOutputs[1].AdditionalData)
+
+	// byte for byte
+	assert.Equal(t, testTx, tx.Hex())
+	assert.Equal(t, 64, len(tx.Id()))
+}
+
+func TestEncodeUnsignedTx(t *testing.T) {
+
+	multisig, err := address.NewP2MPKH([][]byte{{2}, {3}}, 1)
+	assert.Nil(t, err)
+	tx := UnsignedTx{
+		Version:   0,
+		NetworkId: 2,
+		GasAmount: 20000,
+		GasPrice:  big.NewInt(100000000000),
+		Inputs: []Input{{
+			Hint: 1,
+			Key:  make([]byte, HashLength),
+			UnlockScript: UnlockScript{Type: UnlockP2MPKH, PublicKeys: []IndexedPublicKey{
+				{PublicKey: make([]byte, PublicKeyLength), Index: 1},
+			}},
+		}},
+		FixedOutputs: []Output{{Amount: big.NewInt(1), Address: multisig}},
+	}
+	decoded, err := DecodeUnsignedTx(tx.Encode())
+	assert.Nil(t, err)
+	assert.Equal(t, tx.Hex(), decoded.Hex())
+	assert.Equal(t, int32(1), decoded.Inputs[0].UnlockScript.PublicKeys[0].Index)
+	assert.Equal(t, multisig.String(), decoded.FixedOutputs[0].Address.String())
+}
+
+func TestDecodeUnsignedTxWithScript(t *testing.T) {
+
+	script := "0101000000000102a0"
+	withScript := testTx[:4] + "01" + script + testTx[6:]
+	tx, err := DecodeUnsignedTxHex(withScript)
+	assert.Nil(t, err)
+	assert.Equal(t, script, hex.EncodeToString(tx.Script))
+	assert.Equal(t, int32(20000), tx.GasAmount)
+	assert.Equal(t, 1, len(tx.Inputs))
+	assert.Equal(t, 2, len(tx.FixedOutputs))
+	assert.Equal(t, withScript, tx.Hex())
+}
+
+func TestDecodeInvalidUnsignedTx(t *testing.T) {
+
+	_, err := DecodeUnsignedTxHex("000101")
+	assert.NotNil(t, err)
+	// the lengths are not trusted
+	_, err = DecodeUnsignedTx([]byte{0, 0, 0, 0, 0, 0x9f, 0xff, 0xff, 0xff})
+	assert.True(t, errors.Is(err, serde.ErrUnexpectedEnd))
+	_, err = DecodeUnsignedTxHex(testTx + "00")
+	assert.True(t, errors.Is(err, ErrTrailingBytes))
+	_, err = DecodeUnsignedTxHex(testTx[:len(testTx)-10])
+	assert.NotNil(t, err)
+	_, err = DecodeUnsignedTxHex("zz")
+	assert.NotNil(t, err)
+}
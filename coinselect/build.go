@@ -0,0 +1,129 @@
+package coinselect
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/codec"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// BuildParams are the parameters of a transaction built from a selection
+type BuildParams struct {
+	// NetworkId is the id of the network, e.g. the alephium.network.network-id of the node configuration
+	NetworkId byte
+	// Groups is the number of groups of the clique
+	Groups int
+	// FromPublicKey is the public key of the address owning the UTXOs, receiving the change
+	FromPublicKey string
+	Destinations  []alephium.TransactionDestination
+}
+
+// Build builds the unsigned transaction spending the selected UTXOs, to be signed and submitted
+// with SubmitTransaction. The destinations must add up to the target of the selection, each with at
+// least codec.DustUtxoAmount, and their tokens be covered by the tokens of the selected UTXOs, see
+// Options.Tokens, the rest of which goes back with the change.
+func Build(selection Selection, params BuildParams) (alephium.UnsignedTransaction, error) {
+	publicKey, err := hex.DecodeString(params.FromPublicKey)
+	if err != nil || len(publicKey) != codec.PublicKeyLength {
+		return alephium.UnsignedTransaction{}, fmt.Errorf("invalid public key %s", params.FromPublicKey)
+	}
+	if params.Groups <= 0 {
+		return alephium.UnsignedTransaction{}, fmt.Errorf("invalid number of groups %d", params.Groups)
+	}
+	if selection.GasPrice == nil || selection.Target.Amount == nil {
+		return alephium.UnsignedTransaction{}, fmt.Errorf("invalid selection without gas price or target")
+	}
+	from := address.NewP2PKH(publicKey)
+
+	tx := codec.UnsignedTx{
+		NetworkId: params.NetworkId,
+		GasAmount: selection.GasAmount,
+		GasPrice:  selection.GasPrice,
+	}
+	for _, utxo := range selection.Utxos {
+		key, err := hex.DecodeString(utxo.Ref.Key)
+		if err != nil || len(key) != codec.HashLength {
+			return alephium.UnsignedTransaction{}, fmt.Errorf("invalid output ref key %s", utxo.Ref.Key)
+		}
+		tx.Inputs = append(tx.Inputs, codec.NewP2PKHInput(uint32(utxo.Ref.Hint), key, publicKey))
+	}
+
+	paid := new(big.Int)
+	remaining := make(map[string]*big.Int, len(selection.Tokens))
+	for id, amount := range selection.Tokens {
+		remaining[strings.ToLower(id)] = new(big.Int).Set(amount)
+	}
+	toGroup := from.Group(params.Groups)
+	for i, destination := range params.Destinations {
+		to, err := address.Decode(destination.Address)
+		if err != nil {
+			return alephium.UnsignedTransaction{}, fmt.Errorf("%w %q: %v", alephium.ErrInvalidAddress, destination.Address, err)
+		}
+		if destination.Amount.Amount == nil || destination.Amount.Amount.Cmp(codec.DustUtxoAmount) < 0 {
+			return alephium.UnsignedTransaction{}, fmt.Errorf("invalid amount %s to %s, below the dust amount", destination.Amount, destination.Address)
+		}
+		if i == 0 {
+			toGroup = to.Group(params.Groups)
+		}
+		if destination.LockTime < 0 {
+			return alephium.UnsignedTransaction{}, fmt.Errorf("invalid lock time %d to %s", destination.LockTime, destination.Address)
+		}
+		paid.Add(paid, destination.Amount.Amount)
+		output := codec.NewOutput(destination.Amount.Amount, to)
+		output.LockTime = uint64(destination.LockTime)
+		for _, token := range destination.Tokens {
+			tokenId, err := hex.DecodeString(token.Id)
+			if err != nil || len(tokenId) != codec.HashLength {
+				return alephium.UnsignedTransaction{}, fmt.Errorf("invalid token id %s", token.Id)
+			}
+			id := hex.EncodeToString(tokenId)
+			if token.Amount.Value == nil || token.Amount.Value.Sign() <= 0 || remaining[id] == nil || remaining[id].Cmp(token.Amount.Value) < 0 {
+				return alephium.UnsignedTransaction{}, fmt.Errorf("the selection doesn't cover %s of token %s to %s", token.Amount, token.Id, destination.Address)
+			}
+			remaining[id].Sub(remaining[id], token.Amount.Value)
+			if remaining[id].Sign() == 0 {
+				delete(remaining, id)
+			}
+			output.Tokens = append(output.Tokens, codec.Token{Id: tokenId, Amount: token.Amount.Value})
+		}
+		tx.FixedOutputs = append(tx.FixedOutputs, output)
+	}
+	if paid.Cmp(selection.Target.Amount) != 0 {
+		return alephium.UnsignedTransaction{}, fmt.Errorf("the destinations pay %s, the selection was made for %s", paid, selection.Target)
+	}
+
+	changeAmount := selection.Change.Amount
+	if changeAmount == nil {
+		changeAmount = new(big.Int)
+	}
+	if changeAmount.Sign() > 0 || len(remaining) > 0 {
+		if changeAmount.Cmp(codec.DustUtxoAmount) < 0 {
+			return alephium.UnsignedTransaction{}, fmt.Errorf("the change %s is below the dust amount", alephium.ALPH{Amount: changeAmount})
+		}
+		change := codec.NewOutput(changeAmount, from)
+		ids := make([]string, 0, len(remaining))
+		for id := range remaining {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			tokenId, err := hex.DecodeString(id)
+			if err != nil || len(tokenId) != codec.HashLength {
+				return alephium.UnsignedTransaction{}, fmt.Errorf("invalid token id %s", id)
+			}
+			change.Tokens = append(change.Tokens, codec.Token{Id: tokenId, Amount: remaining[id]})
+		}
+		tx.FixedOutputs = append(tx.FixedOutputs, change)
+	}
+
+	return alephium.UnsignedTransaction{
+		UnsignedTx: tx.Hex(),
+		TxId:       tx.Id(),
+		FromGroup:  from.Group(params.Groups),
+		ToGroup:    toGroup,
+	}, nil
+}
@@ -0,0 +1,421 @@
+// Package coinselect selects the UTXOs spent by a transaction, and builds the transaction locally
+// from them, instead of letting the node choose its inputs.
+//
+// The selection is deterministic: for the same UTXOs and options, the same inputs are selected,
+// ties between amounts being broken by output key. Reservations make concurrent selections, e.g.
+// withdrawals of an exchange, never select the same UTXOs.
+package coinselect
+
+import (
+	"errors"
+	"fmt"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/codec"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Strategy is the order in which the UTXOs are selected
+type Strategy int
+
+const (
+	// LargestFirst selects the largest UTXOs first, minimizing the number of inputs
+	LargestFirst Strategy = iota
+	// SmallestFirst selects the smallest UTXOs first, reducing the number of UTXOs left
+	SmallestFirst
+	// BranchAndBound searches the combination of UTXOs leaving the smallest change, ideally none,
+	// and falls back to LargestFirst if the search is exhausted
+	BranchAndBound
+	// Consolidation selects as many UTXOs as a transaction can spend, smallest first, skipping the ones
+	// worth less than the gas of their input. Larger UTXOs are selected if the smallest don't cover the gas.
+	Consolidation
+)
+
+const branchAndBoundTries = 100000
+
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// Options configures the selection. All the fields are optional.
+type Options struct {
+	Strategy Strategy
+	// Outputs is the number of outputs paid by the transaction, change excluded, 1 if 0, or none for
+	// a Consolidation without target, the UTXOs selected being merged into the change
+	Outputs int
+	// GasPrice is codec.DefaultGasPrice if nil
+	GasPrice *big.Int
+	// MaxInputs is the maximum number of inputs, as many as the maximal gas allows if 0
+	MaxInputs int
+	// Now is the time at which the lock time of the UTXOs is checked, time.Now() if zero
+	Now time.Time
+	// Exclude skips the UTXOs it returns true for
+	Exclude func(alephium.OutputRef) bool
+	// Tokens are the amounts of tokens paid to the outputs, by token id. The UTXOs holding the most of
+	// them are selected first, whatever the strategy, the ALPH being selected with the strategy then.
+	Tokens map[string]*big.Int
+}
+
+// Selection is the result of the selection
+type Selection struct {
+	Utxos []alephium.Utxo
+	// Total is the amount of the UTXOs selected
+	Total alephium.ALPH
+	// Target is the amount paid to the outputs
+	Target    alephium.ALPH
+	GasAmount int32
+	GasPrice  *big.Int
+	// Change is what is left after paying the target and the gas, sent back to the sender.
+	// It is either 0 or at least codec.DustUtxoAmount.
+	Change alephium.ALPH
+	// Tokens are the tokens of the UTXOs selected, by token id. What is not paid to the outputs,
+	// i.e. TokenTarget, is sent back to the sender with the change.
+	Tokens map[string]*big.Int
+	// TokenTarget are the tokens paid to the outputs, by token id
+	TokenTarget map[string]*big.Int
+}
+
+// HasChange is true if the transaction needs a change output
+func (s Selection) HasChange() bool {
+	return (s.Change.Amount != nil && s.Change.Amount.Sign() > 0) || tokensLeft(s.Tokens, s.TokenTarget)
+}
+
+// tokensLeft is true if some tokens are not paid to the outputs
+func tokensLeft(tokens map[string]*big.Int, target map[string]*big.Int) bool {
+	for id, amount := range tokens {
+		if t, ok := target[id]; !ok || amount.Cmp(t) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Select selects the UTXOs paying the target amount and the gas
+func Select(utxos []alephium.Utxo, target alephium.ALPH, options Options) (Selection, error) {
+	s := newSelector(target, options)
+	candidates := s.candidates(utxos)
+	required, candidates, ok := s.tokenInputs(candidates)
+	if !ok {
+		return Selection{}, fmt.Errorf("%w: the spendable UTXOs don't hold the tokens required", ErrInsufficientFunds)
+	}
+	if len(required) > s.maxInputs {
+		return Selection{}, fmt.Errorf("%w: the tokens required are spread over more than %d UTXOs", ErrInsufficientFunds, s.maxInputs)
+	}
+	s.required = required
+
+	var selection Selection
+	switch options.Strategy {
+	case LargestFirst:
+		selection, ok = s.greedy(reversed(candidates))
+	case SmallestFirst:
+		selection, ok = s.greedy(candidates)
+	case BranchAndBound:
+		selection, ok = s.branchAndBound(reversed(candidates))
+		if !ok {
+			selection, ok = s.greedy(reversed(candidates))
+		}
+	case Consolidation:
+		selection, ok = s.consolidation(candidates)
+	default:
+		return Selection{}, fmt.Errorf("unknown strategy %d", options.Strategy)
+	}
+	if !ok {
+		return Selection{}, fmt.Errorf("%w: %s required, plus gas, from %d spendable UTXOs", ErrInsufficientFunds, target, len(candidates))
+	}
+	return selection, nil
+}
+
+type selector struct {
+	target      *big.Int
+	tokenTarget map[string]*big.Int
+	outputs     int
+	gasPrice    *big.Int
+	maxInputs   int
+	now         time.Time
+	exclude     func(alephium.OutputRef) bool
+	// required are the UTXOs selected for their tokens, spent whatever the strategy
+	required []alephium.Utxo
+}
+
+func newSelector(target alephium.ALPH, options Options) *selector {
+	s := &selector{
+		target:    target.Amount,
+		outputs:   options.Outputs,
+		gasPrice:  options.GasPrice,
+		maxInputs: options.MaxInputs,
+		now:       options.Now,
+		exclude:   options.Exclude,
+	}
+	if s.target == nil {
+		s.target = new(big.Int)
+	}
+	if len(options.Tokens) > 0 {
+		s.tokenTarget = make(map[string]*big.Int, len(options.Tokens))
+		for id, amount := range options.Tokens {
+			if amount != nil && amount.Sign() > 0 {
+				s.tokenTarget[strings.ToLower(id)] = new(big.Int).Set(amount)
+			}
+		}
+	}
+	if s.outputs <= 0 && (options.Strategy != Consolidation || s.target.Sign() > 0) {
+		s.outputs = 1
+	}
+	if s.gasPrice == nil {
+		s.gasPrice = codec.DefaultGasPrice
+	}
+	if s.maxInputs <= 0 {
+		for codec.EstimateP2PKHGas(s.maxInputs+1, s.outputs+1) <= codec.MaximalGasPerTx {
+			s.maxInputs++
+		}
+	}
+	if s.now.IsZero() {
+		s.now = time.Now()
+	}
+	return s
+}
+
+// candidates returns the spendable UTXOs, smallest first
+func (s *selector) candidates(utxos []alephium.Utxo) []alephium.Utxo {
+	candidates := make([]alephium.Utxo, 0, len(utxos))
+	for _, utxo := range utxos {
+		if utxo.Amount.Amount == nil || !utxo.Spendable(s.now) {
+			continue
+		}
+		if s.exclude != nil && s.exclude(utxo.Ref) {
+			continue
+		}
+		candidates = append(candidates, utxo)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if c := candidates[i].Amount.Cmp(candidates[j].Amount); c != 0 {
+			return c < 0
+		}
+		return candidates[i].Ref.Key < candidates[j].Ref.Key
+	})
+	return candidates
+}
+
+// tokenInputs selects, token by token, the candidates holding the most of it until its target is
+// reached, and returns them with the candidates left
+func (s *selector) tokenInputs(candidates []alephium.Utxo) ([]alephium.Utxo, []alephium.Utxo, bool) {
+	ids := make([]string, 0, len(s.tokenTarget))
+	for id := range s.tokenTarget {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	selected := make(map[string]bool)
+	var required []alephium.Utxo
+	for _, id := range ids {
+		held := new(big.Int)
+		for _, utxo := range required {
+			held.Add(held, tokenAmount(utxo, id))
+		}
+		holders := make([]alephium.Utxo, 0)
+		for _, utxo := range candidates {
+			if !selected[utxo.Ref.Key] && tokenAmount(utxo, id).Sign() > 0 {
+				holders = append(holders, utxo)
+			}
+		}
+		sort.SliceStable(holders, func(i, j int) bool {
+			if c := tokenAmount(holders[i], id).Cmp(tokenAmount(holders[j], id)); c != 0 {
+				return c > 0
+			}
+			return holders[i].Ref.Key < holders[j].Ref.Key
+		})
+		for _, utxo := range holders {
+			if held.Cmp(s.tokenTarget[id]) >= 0 {
+				break
+			}
+			held.Add(held, tokenAmount(utxo, id))
+			required = append(required, utxo)
+			selected[utxo.Ref.Key] = true
+		}
+		if held.Cmp(s.tokenTarget[id]) < 0 {
+			return nil, nil, false
+		}
+	}
+
+	left := make([]alephium.Utxo, 0, len(candidates)-len(required))
+	for _, utxo := range candidates {
+		if !selected[utxo.Ref.Key] {
+			left = append(left, utxo)
+		}
+	}
+	return required, left, true
+}
+
+// tokenAmount is the amount of the token held by the UTXO
+func tokenAmount(utxo alephium.Utxo, id string) *big.Int {
+	amount := new(big.Int)
+	for _, token := range utxo.Tokens {
+		if token.Amount.Value != nil && strings.ToLower(token.Id) == id {
+			amount.Add(amount, token.Amount.Value)
+		}
+	}
+	return amount
+}
+
+// worthSpending returns the candidates worth more than the gas of their input
+func (s *selector) worthSpending(candidates []alephium.Utxo) []alephium.Utxo {
+	inputFee := codec.GasFee(codec.TxInputBaseGas+codec.P2PKHUnlockGas, s.gasPrice)
+	worth := make([]alephium.Utxo, 0, len(candidates))
+	for _, utxo := range candidates {
+		if utxo.Amount.Amount.Cmp(inputFee) > 0 {
+			worth = append(worth, utxo)
+		}
+	}
+	return worth
+}
+
+// consolidation selects as many of the smallest UTXOs worth spending as possible, sliding to larger
+// ones until the gas is covered
+func (s *selector) consolidation(candidates []alephium.Utxo) (Selection, bool) {
+	worth := s.worthSpending(candidates)
+	n := len(worth)
+	if n > s.maxInputs-len(s.required) {
+		n = s.maxInputs - len(s.required)
+	}
+	for start := 0; start+n <= len(worth); start++ {
+		if selection, ok := s.finalize(worth[start : start+n]); ok {
+			return selection, true
+		}
+	}
+	return Selection{}, false
+}
+
+// greedy selects the UTXOs in order until the target and the gas are paid
+func (s *selector) greedy(ordered []alephium.Utxo) (Selection, bool) {
+	for n := 0; n <= len(ordered) && n+len(s.required) <= s.maxInputs; n++ {
+		if selection, ok := s.finalize(ordered[:n]); ok {
+			return selection, true
+		}
+	}
+	return Selection{}, false
+}
+
+// branchAndBound searches, largest first, the combination of UTXOs leaving the smallest change
+func (s *selector) branchAndBound(ordered []alephium.Utxo) (Selection, bool) {
+	remaining := make([]*big.Int, len(ordered)+1)
+	remaining[len(ordered)] = new(big.Int)
+	for i := len(ordered) - 1; i >= 0; i-- {
+		remaining[i] = new(big.Int).Add(remaining[i+1], ordered[i].Amount.Amount)
+	}
+
+	var best Selection
+	found := false
+	tries := 0
+	selected := make([]alephium.Utxo, 0)
+	total := new(big.Int)
+	for _, utxo := range s.required {
+		total.Add(total, utxo.Amount.Amount)
+	}
+
+	var search func(i int) bool
+	search = func(i int) bool {
+		tries++
+		if tries > branchAndBoundTries {
+			return true
+		}
+		if selection, ok := s.finalize(selected); ok {
+			if !found || selection.Change.Cmp(best.Change) < 0 {
+				best, found = selection, true
+			}
+			// adding inputs only increases the change
+			return selection.Change.Amount.Sign() == 0
+		}
+		if i == len(ordered) || len(selected)+len(s.required) == s.maxInputs {
+			return false
+		}
+		// even with all the remaining UTXOs, the target can't be reached
+		if new(big.Int).Add(total, remaining[i]).Cmp(s.target) < 0 {
+			return false
+		}
+		// prune the branches whose total already exceeds the best one
+		if found && total.Cmp(best.Total.Amount) >= 0 {
+			return false
+		}
+
+		selected = append(selected, ordered[i])
+		total.Add(total, ordered[i].Amount.Amount)
+		if search(i + 1) {
+			return true
+		}
+		selected = selected[:len(selected)-1]
+		total.Sub(total, ordered[i].Amount.Amount)
+		return search(i + 1)
+	}
+	search(0)
+	return best, found
+}
+
+// finalize computes the gas and the change of the selected UTXOs, along with the required ones,
+// false if they are not enough
+func (s *selector) finalize(selected []alephium.Utxo) (Selection, bool) {
+	utxos := append(append([]alephium.Utxo(nil), s.required...), selected...)
+	if len(utxos) == 0 {
+		return Selection{}, false
+	}
+	total := new(big.Int)
+	tokens := make(map[string]*big.Int)
+	for _, utxo := range utxos {
+		total.Add(total, utxo.Amount.Amount)
+		for _, token := range utxo.Tokens {
+			if token.Amount.Value == nil {
+				continue
+			}
+			id := strings.ToLower(token.Id)
+			if _, ok := tokens[id]; !ok {
+				tokens[id] = new(big.Int)
+			}
+			tokens[id].Add(tokens[id], token.Amount.Value)
+		}
+	}
+	for id, amount := range s.tokenTarget {
+		if tokens[id] == nil || tokens[id].Cmp(amount) < 0 {
+			return Selection{}, false
+		}
+	}
+	selection := Selection{
+		Utxos:       utxos,
+		Total:       alephium.ALPH{Amount: total},
+		Target:      alephium.ALPH{Amount: new(big.Int).Set(s.target)},
+		GasPrice:    s.gasPrice,
+		TokenTarget: s.tokenTarget,
+	}
+	if len(tokens) > 0 {
+		selection.Tokens = tokens
+	}
+
+	// without change output first, a change below the dust amount being paid as gas if it can be:
+	// the fee is exactly the gas amount times the gas price
+	if s.outputs > 0 && !tokensLeft(tokens, s.tokenTarget) {
+		gas := codec.EstimateP2PKHGas(len(utxos), s.outputs)
+		change := new(big.Int).Sub(total, s.target)
+		change.Sub(change, codec.GasFee(gas, s.gasPrice))
+		if change.Sign() >= 0 && change.Cmp(codec.DustUtxoAmount) < 0 {
+			extraGas, rest := new(big.Int).QuoRem(change, s.gasPrice, new(big.Int))
+			if rest.Sign() == 0 && extraGas.Int64() <= int64(codec.MaximalGasPerTx-gas) {
+				selection.GasAmount, selection.Change = gas+int32(extraGas.Int64()), alephium.ALPH{Amount: new(big.Int)}
+				return selection, true
+			}
+		}
+	}
+	// the change output can't be dust either, more UTXOs are needed then
+	gas := codec.EstimateP2PKHGas(len(utxos), s.outputs+1)
+	change := new(big.Int).Sub(total, s.target)
+	change.Sub(change, codec.GasFee(gas, s.gasPrice))
+	if change.Cmp(codec.DustUtxoAmount) < 0 {
+		return Selection{}, false
+	}
+	selection.GasAmount, selection.Change = gas, alephium.ALPH{Amount: change}
+	return selection, true
+}
+
+func reversed(utxos []alephium.Utxo) []alephium.Utxo {
+	r := make([]alephium.Utxo, len(utxos))
+	for i, utxo := range utxos {
+		r[len(utxos)-1-i] = utxo
+	}
+	return r
+}
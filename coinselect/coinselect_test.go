@@ -0,0 +1,218 @@
+package coinselect
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/codec"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPublicKey = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+
+func alph(s string) alephium.ALPH {
+	a, _ := alephium.ALPHFromALPHString(s)
+	return a
+}
+
+func utxo(amount string, key int) alephium.Utxo {
+	return alephium.Utxo{
+		Ref:    alephium.OutputRef{Hint: key, Key: fmt.Sprintf("%064x", key)},
+		Amount: alph(amount),
+	}
+}
+
+func keys(selection Selection) []int {
+	k := make([]int, len(selection.Utxos))
+	for i, u := range selection.Utxos {
+		k[i] = u.Ref.Hint
+	}
+	return k
+}
+
+func TestStrategies(t *testing.T) {
+
+	utxos := []alephium.Utxo{utxo("3", 1), utxo("0.5", 2), utxo("0.7", 3), utxo("0.502", 4), utxo("0.1", 5)}
+
+	selection, err := Select(utxos, alph("1"), Options{Strategy: LargestFirst})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1}, keys(selection))
+	// 3 - 1 - 20000 gas at 100 nanoALPH
+	assert.Equal(t, alph("1.998").String(), selection.Change.String())
+	assert.Equal(t, codec.MinimalGas, selection.GasAmount)
+
+	selection, err = Select(utxos, alph("1"), Options{Strategy: SmallestFirst})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{5, 2, 4}, keys(selection))
+
+	// 0.5 + 0.502 pays 1 plus the gas exactly, no change
+	selection, err = Select(utxos, alph("1"), Options{Strategy: BranchAndBound})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []int{2, 4}, keys(selection))
+	assert.False(t, selection.HasChange())
+
+	selection, err = Select(utxos, alph("0"), Options{Strategy: Consolidation, MaxInputs: 4})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{5, 2, 4, 3}, keys(selection))
+
+	_, err = Select(utxos, alph("5"), Options{Strategy: LargestFirst})
+	assert.True(t, errors.Is(err, ErrInsufficientFunds))
+}
+
+func TestDustChange(t *testing.T) {
+
+	// 1.0025 - 1 - 0.002 of gas leaves 0.0005, below the dust amount, paid with 5000 more gas
+	selection, err := Select([]alephium.Utxo{utxo("1.0025", 1)}, alph("1"), Options{})
+	assert.Nil(t, err)
+	assert.False(t, selection.HasChange())
+	assert.Equal(t, codec.MinimalGas+5000, selection.GasAmount)
+
+	// 1 attoALPH can't be paid as gas, another UTXO is needed
+	dusty := utxo("1.002", 1)
+	dusty.Amount = alephium.ALPH{Amount: new(big.Int).Add(dusty.Amount.Amount, big.NewInt(1))}
+	selection, err = Select([]alephium.Utxo{dusty, utxo("1", 2)}, alph("1"), Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, keys(selection))
+	assert.True(t, selection.Change.Cmp(alephium.ALPH{Amount: codec.DustUtxoAmount}) >= 0)
+
+	// the change output of the token needs some ALPH too
+	withToken := utxo("1.002", 1)
+	withToken.Tokens = []alephium.Token{{Id: strings.Repeat("ab", 32), Amount: alephium.U256{Value: big.NewInt(7)}}}
+	selection, err = Select([]alephium.Utxo{withToken, utxo("1", 2)}, alph("1"), Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, keys(selection))
+
+	// a hand-built selection is checked
+	_, err = Build(Selection{}, BuildParams{Groups: 4, FromPublicKey: testPublicKey})
+	assert.NotNil(t, err)
+}
+
+func TestTokenTargets(t *testing.T) {
+
+	tokenId := strings.Repeat("ab", 32)
+	withTokens := func(amount string, key int, tokens int64) alephium.Utxo {
+		u := utxo(amount, key)
+		u.Tokens = []alephium.Token{{Id: tokenId, Amount: alephium.U256{Value: big.NewInt(tokens)}}}
+		return u
+	}
+	utxos := []alephium.Utxo{utxo("10", 1), withTokens("0.5", 2, 3), withTokens("0.5", 3, 5), withTokens("0.5", 4, 1)}
+
+	// the UTXOs holding the most tokens first, then the ALPH
+	selection, err := Select(utxos, alph("2"), Options{Tokens: map[string]*big.Int{tokenId: big.NewInt(6)}})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{3, 2, 1}, keys(selection))
+	assert.True(t, selection.HasChange())
+
+	destinations := []alephium.TransactionDestination{{
+		Address: "1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi",
+		Amount:  alph("2"),
+		Tokens:  []alephium.Token{{Id: tokenId, Amount: alephium.U256{Value: big.NewInt(6)}}},
+	}}
+	unsignedTx, err := Build(selection, BuildParams{Groups: 4, FromPublicKey: testPublicKey, Destinations: destinations})
+	assert.Nil(t, err)
+	assert.Nil(t, alephium.VerifyUnsignedTransaction(unsignedTx, testPublicKey, destinations))
+	tx, err := codec.DecodeUnsignedTxHex(unsignedTx.UnsignedTx)
+	assert.Nil(t, err)
+	assert.Equal(t, "2", tx.FixedOutputs[1].Tokens[0].Amount.String())
+
+	_, err = Select(utxos, alph("2"), Options{Tokens: map[string]*big.Int{tokenId: big.NewInt(10)}})
+	assert.True(t, errors.Is(err, ErrInsufficientFunds))
+}
+
+func TestSelectionIsDeterministic(t *testing.T) {
+
+	utxos := []alephium.Utxo{utxo("1", 3), utxo("1", 1), utxo("1", 2), utxo("2", 4)}
+	shuffled := []alephium.Utxo{utxos[2], utxos[3], utxos[0], utxos[1]}
+	for _, strategy := range []Strategy{LargestFirst, SmallestFirst, BranchAndBound, Consolidation} {
+		s1, err := Select(utxos, alph("1.5"), Options{Strategy: strategy})
+		assert.Nil(t, err)
+		s2, err := Select(shuffled, alph("1.5"), Options{Strategy: strategy})
+		assert.Nil(t, err)
+		assert.Equal(t, keys(s1), keys(s2))
+	}
+}
+
+func TestLockTime(t *testing.T) {
+
+	now := time.Now()
+	locked := utxo("10", 1)
+	locked.LockTime = now.Add(time.Hour).UnixNano() / int64(time.Millisecond)
+	utxos := []alephium.Utxo{locked, utxo("1", 2)}
+
+	_, err := Select(utxos, alph("5"), Options{Now: now})
+	assert.True(t, errors.Is(err, ErrInsufficientFunds))
+	selection, err := Select(utxos, alph("5"), Options{Now: now.Add(2 * time.Hour)})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1}, keys(selection))
+}
+
+func TestReservations(t *testing.T) {
+
+	utxos := []alephium.Utxo{utxo("1", 1), utxo("1", 2), utxo("1", 3)}
+	reservations := NewReservations()
+
+	s1, err := reservations.Select(utxos, alph("0.5"), Options{})
+	assert.Nil(t, err)
+	s2, err := reservations.Select(utxos, alph("0.5"), Options{})
+	assert.Nil(t, err)
+	assert.NotEqual(t, keys(s1), keys(s2))
+	assert.True(t, reservations.Reserved(s1.Utxos[0].Ref))
+
+	reservations.Release(s1)
+	assert.False(t, reservations.Reserved(s1.Utxos[0].Ref))
+	s3, err := reservations.Select(utxos, alph("0.5"), Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, keys(s1), keys(s3))
+}
+
+func TestBuild(t *testing.T) {
+
+	token := alephium.Token{Id: strings.Repeat("ab", 32), Amount: alephium.U256{Value: big.NewInt(7)}}
+	withToken := utxo("2", 2)
+	withToken.Tokens = []alephium.Token{token}
+	utxos := []alephium.Utxo{utxo("3", 1), withToken}
+
+	destinations := []alephium.TransactionDestination{
+		{Address: "1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi", Amount: alph("2")},
+		{Address: "1Ambgi1jNRcBcdDUSfyrY2uQXdHpJs3zfc7Nmt6NcpBbL", Amount: alph("2.5")},
+	}
+	selection, err := Select(utxos, alph("4.5"), Options{Outputs: 2})
+	assert.Nil(t, err)
+
+	unsignedTx, err := Build(selection, BuildParams{NetworkId: 1, Groups: 4, FromPublicKey: testPublicKey, Destinations: destinations})
+	assert.Nil(t, err)
+	assert.Nil(t, alephium.VerifyUnsignedTransaction(unsignedTx, testPublicKey, destinations))
+	assert.Equal(t, 0, unsignedTx.ToGroup)
+
+	tx, err := codec.DecodeUnsignedTxHex(unsignedTx.UnsignedTx)
+	assert.Nil(t, err)
+	assert.Equal(t, byte(1), tx.NetworkId)
+	assert.Equal(t, 2, len(tx.Inputs))
+	assert.Equal(t, 3, len(tx.FixedOutputs))
+	change := tx.FixedOutputs[2]
+	assert.Equal(t, selection.Change.Amount, change.Amount)
+	assert.Equal(t, "7", change.Tokens[0].Amount.String())
+
+	_, err = Build(selection, BuildParams{Groups: 4, FromPublicKey: testPublicKey, Destinations: destinations[:1]})
+	assert.NotNil(t, err)
+
+	// part of the token is sent, locked, to the first destination
+	destinations[0].Tokens = []alephium.Token{{Id: token.Id, Amount: alephium.U256{Value: big.NewInt(5)}}}
+	destinations[0].LockTime = 1640995200000
+	unsignedTx, err = Build(selection, BuildParams{NetworkId: 1, Groups: 4, FromPublicKey: testPublicKey, Destinations: destinations})
+	assert.Nil(t, err)
+	assert.Nil(t, alephium.VerifyUnsignedTransaction(unsignedTx, testPublicKey, destinations))
+	tx, err = codec.DecodeUnsignedTxHex(unsignedTx.UnsignedTx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1640995200000), tx.FixedOutputs[0].LockTime)
+	assert.Equal(t, "5", tx.FixedOutputs[0].Tokens[0].Amount.String())
+	assert.Equal(t, "2", tx.FixedOutputs[2].Tokens[0].Amount.String())
+
+	destinations[0].Tokens[0].Amount = alephium.U256{Value: big.NewInt(8)}
+	_, err = Build(selection, BuildParams{NetworkId: 1, Groups: 4, FromPublicKey: testPublicKey, Destinations: destinations})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,175 @@
+package coinselect
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/codec"
+	"math/big"
+)
+
+const (
+	// DefaultConsolidationInputs is the number of UTXOs merged by a consolidation transaction
+	DefaultConsolidationInputs = 100
+)
+
+// ConsolidateOptions configures ConsolidateUtxos
+type ConsolidateOptions struct {
+	// NetworkId is the network id of the node, e.g. the alephium.network.network-id of its configuration
+	NetworkId byte
+	// Groups is the number of groups of the clique, fetched from the node if 0
+	Groups int
+	// MaxInputs is the number of UTXOs merged by a transaction, DefaultConsolidationInputs if 0.
+	// It is lowered if the transaction would exceed the maximal gas.
+	MaxInputs int
+	// TargetUtxos is the number of UTXOs under which the consolidation stops, 1 if 0
+	TargetUtxos int
+	// GasPrice is codec.DefaultGasPrice if nil
+	GasPrice *big.Int
+	// GasBudget is the total gas fee the consolidation may spend, unlimited if nil
+	GasBudget *big.Int
+	// MaxUtxos caps the number of UTXOs listed by GetAddressUtxos before every transaction, the node default
+	// if 0. The node can't page through them: the UTXOs not listed are merged by the next transactions.
+	MaxUtxos int
+	// Log is the logger of the consolidation, nothing is logged if nil
+	Log alephium.Logger
+}
+
+// ConsolidationResult reports what ConsolidateUtxos did
+type ConsolidationResult struct {
+	Transactions []alephium.Transaction
+	// Inputs is the number of UTXOs merged
+	Inputs int
+	// GasFee is the total gas fee paid
+	GasFee alephium.ALPH
+	// BudgetExhausted is true if the consolidation stopped because of the gas budget
+	BudgetExhausted bool
+}
+
+// ConsolidateUtxos merges the UTXOs of an address of the wallet with self transfers of up to MaxInputs
+// inputs, selected with the Consolidation strategy and built with Build, until the address has TargetUtxos
+// UTXOs worth spending or the gas budget is spent.
+// The transactions are signed by the wallet, whose active address is changed to the given address during
+// the consolidation. Every transaction is confirmed before the next one is built.
+func ConsolidateUtxos(ctx context.Context, client *alephium.Client, walletName string, addr string,
+	opts ConsolidateOptions) (ConsolidationResult, error) {
+
+	result := ConsolidationResult{GasFee: alephium.ALPH{Amount: new(big.Int)}}
+
+	lockup, err := address.Decode(addr)
+	if err != nil {
+		return result, fmt.Errorf("%w %q: %v", alephium.ErrInvalidAddress, addr, err)
+	}
+	if lockup.Type() != address.P2PKH {
+		return result, fmt.Errorf("%w %q: only P2PKH addresses can be consolidated", alephium.ErrInvalidAddress, addr)
+	}
+	if opts.MaxInputs <= 0 {
+		opts.MaxInputs = DefaultConsolidationInputs
+	}
+	for opts.MaxInputs > 2 && codec.EstimateP2PKHGas(opts.MaxInputs, 1) > codec.MaximalGasPerTx {
+		opts.MaxInputs--
+	}
+	if opts.TargetUtxos <= 0 {
+		opts.TargetUtxos = 1
+	}
+	if opts.GasPrice == nil {
+		opts.GasPrice = codec.DefaultGasPrice
+	}
+	log := opts.Log
+	if log == nil {
+		log = alephium.NopLogger{}
+	}
+	if opts.Groups <= 0 {
+		infos, err := client.GetSelfCliqueInfosCtx(ctx)
+		if err != nil {
+			return result, err
+		}
+		opts.Groups = infos.Groups
+	}
+
+	detail, err := client.GetWalletAddressDetailCtx(ctx, walletName, addr)
+	if err != nil {
+		return result, err
+	}
+	if publicKey, err := hex.DecodeString(detail.PublicKey); err != nil || address.NewP2PKH(publicKey).String() != addr {
+		return result, fmt.Errorf("invalid public key %s of %s", detail.PublicKey, addr)
+	}
+	walletAddresses, err := client.GetWalletAddressesCtx(ctx, walletName)
+	if err != nil {
+		return result, err
+	}
+	if walletAddresses.ActiveAddress != addr {
+		if _, err := client.ChangeActiveAddressCtx(ctx, walletName, addr); err != nil {
+			return result, err
+		}
+		defer func() {
+			if _, err := client.ChangeActiveAddressCtx(context.Background(), walletName, walletAddresses.ActiveAddress); err != nil {
+				log.Warnf("Failed to restore the active address %s of wallet %s: %v", walletAddresses.ActiveAddress, walletName, err)
+			}
+		}()
+	}
+
+	for {
+		utxosList, err := client.GetAddressUtxosCtx(ctx, addr, opts.MaxUtxos)
+		if err != nil {
+			return result, err
+		}
+		s := newSelector(alephium.ALPH{}, Options{Strategy: Consolidation, GasPrice: opts.GasPrice})
+		worth := len(s.worthSpending(s.candidates(utxosList.Utxos)))
+		if worth <= opts.TargetUtxos || worth < 2 {
+			log.Debugf("%d spendable UTXOs worth spending left on %s, consolidation done", worth, addr)
+			return result, nil
+		}
+		// merging n UTXOs into one removes n-1 of them
+		n := worth - opts.TargetUtxos + 1
+		if n > opts.MaxInputs {
+			n = opts.MaxInputs
+		}
+		selection, err := Select(utxosList.Utxos, alephium.ALPH{Amount: new(big.Int)}, Options{
+			Strategy:  Consolidation,
+			GasPrice:  opts.GasPrice,
+			MaxInputs: n,
+		})
+		if errors.Is(err, ErrInsufficientFunds) {
+			log.Infof("The UTXOs of %s don't cover the gas fee of a consolidation, consolidation stopped", addr)
+			return result, nil
+		} else if err != nil {
+			return result, err
+		}
+
+		gasFee := codec.GasFee(selection.GasAmount, selection.GasPrice)
+		if opts.GasBudget != nil && new(big.Int).Add(result.GasFee.Amount, gasFee).Cmp(opts.GasBudget) > 0 {
+			log.Infof("Gas budget of %s exhausted, consolidation of %s stopped", opts.GasBudget, addr)
+			result.BudgetExhausted = true
+			return result, nil
+		}
+
+		unsignedTx, err := Build(selection, BuildParams{
+			NetworkId:     opts.NetworkId,
+			Groups:        opts.Groups,
+			FromPublicKey: detail.PublicKey,
+		})
+		if err != nil {
+			return result, err
+		}
+		signature, err := client.SignCtx(ctx, walletName, unsignedTx.TxId)
+		if err != nil {
+			return result, fmt.Errorf("sign: %w", err)
+		}
+		tx, err := client.SubmitTransactionCtx(ctx, unsignedTx.UnsignedTx, signature)
+		if err != nil {
+			return result, fmt.Errorf("submit: %w", err)
+		}
+		log.Debugf("Consolidating %d UTXOs of %s with tx %s", len(selection.Utxos), addr, tx.TransactionId)
+		if _, err := client.WaitForTransactionConfirmed(ctx, tx.TransactionId, tx.FromGroup, tx.ToGroup); err != nil {
+			return result, err
+		}
+
+		result.Transactions = append(result.Transactions, tx)
+		result.Inputs += len(selection.Utxos)
+		result.GasFee = alephium.ALPH{Amount: new(big.Int).Add(result.GasFee.Amount, gasFee)}
+	}
+}
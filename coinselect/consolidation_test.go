@@ -0,0 +1,195 @@
+package coinselect
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/codec"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type consolidationNode struct {
+	mu            sync.Mutex
+	address       string
+	publicKey     string
+	activeAddress string
+	utxos         []alephium.Utxo
+	submitted     []codec.UnsignedTx
+}
+
+func (n *consolidationNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.URL.Path == "/wallets/w/addresses/"+n.address:
+		_ = json.NewEncoder(w).Encode(alephium.AddressDetailResponse{Address: n.address, PublicKey: n.publicKey})
+	case r.URL.Path == "/wallets/w/addresses":
+		_ = json.NewEncoder(w).Encode(alephium.WalletAddresses{ActiveAddress: n.activeAddress})
+	case r.URL.Path == "/wallets/w/change-active-address":
+		var body alephium.AddressBodyRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		n.activeAddress = body.Address
+	case r.URL.Path == "/addresses/"+n.address+"/utxos":
+		_ = json.NewEncoder(w).Encode(alephium.AddressUtxosList{Utxos: n.utxos})
+	case r.URL.Path == "/wallets/w/sign":
+		_ = json.NewEncoder(w).Encode(alephium.SignResponse{Signature: strings.Repeat("00", 64)})
+	case r.URL.Path == "/transactions/submit":
+		var body alephium.SubmitTransactionBodyRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		tx, err := codec.DecodeUnsignedTxHex(body.UnsignedTx)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		n.submitted = append(n.submitted, tx)
+		spent := make(map[string]bool)
+		for _, input := range tx.Inputs {
+			spent[hex.EncodeToString(input.Key)] = true
+		}
+		utxos := make([]alephium.Utxo, 0)
+		for _, utxo := range n.utxos {
+			if !spent[utxo.Ref.Key] {
+				utxos = append(utxos, utxo)
+			}
+		}
+		n.utxos = append(utxos, alephium.Utxo{
+			Ref:    alephium.OutputRef{Hint: 1, Key: tx.Id()},
+			Amount: alephium.ALPH{Amount: tx.FixedOutputs[0].Amount},
+		})
+		_ = json.NewEncoder(w).Encode(alephium.Transaction{TransactionId: tx.Id()})
+	case r.URL.Path == "/transactions/status":
+		_, _ = w.Write([]byte(`{"type":"confirmed"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newConsolidationNode(t *testing.T, utxos int) *consolidationNode {
+	publicKey := "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	pk, _ := hex.DecodeString(publicKey)
+	node := &consolidationNode{
+		address:       address.NewP2PKH(pk).String(),
+		publicKey:     publicKey,
+		activeAddress: "1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi",
+	}
+	one, _ := alephium.ALPHFromALPHString("1")
+	for i := 0; i < utxos; i++ {
+		node.utxos = append(node.utxos, alephium.Utxo{Ref: alephium.OutputRef{Hint: 1, Key: fmt.Sprintf("%064x", i)}, Amount: one})
+	}
+	return node
+}
+
+func TestConsolidateUtxos(t *testing.T) {
+
+	node := newConsolidationNode(t, 25)
+	ts := httptest.NewServer(node)
+	defer ts.Close()
+	alephiumClient, err := alephium.NewClient(ts.URL, alephium.WithPollInterval(time.Millisecond))
+	assert.Nil(t, err)
+
+	result, err := ConsolidateUtxos(context.Background(), alephiumClient, "w", node.address, ConsolidateOptions{
+		NetworkId: 1,
+		Groups:    4,
+		MaxInputs: 10,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(result.Transactions))
+	assert.Equal(t, 27, result.Inputs)
+	assert.False(t, result.BudgetExhausted)
+	assert.Equal(t, 1, len(node.utxos))
+	// the active address is restored
+	assert.Equal(t, "1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi", node.activeAddress)
+
+	// nothing is lost but the gas
+	total, _ := alephium.ALPHFromALPHString("25")
+	assert.Equal(t, total.Subtract(result.GasFee).String(), node.utxos[0].Amount.String())
+	for _, tx := range node.submitted {
+		assert.Equal(t, byte(1), tx.NetworkId)
+		assert.Equal(t, 1, len(tx.FixedOutputs))
+		assert.Equal(t, node.address, tx.FixedOutputs[0].Address.String())
+	}
+}
+
+func TestConsolidateUtxosGasBudget(t *testing.T) {
+
+	node := newConsolidationNode(t, 25)
+	ts := httptest.NewServer(node)
+	defer ts.Close()
+	alephiumClient, err := alephium.NewClient(ts.URL, alephium.WithPollInterval(time.Millisecond))
+	assert.Nil(t, err)
+
+	budget := codec.GasFee(codec.EstimateP2PKHGas(10, 1), codec.DefaultGasPrice)
+	result, err := ConsolidateUtxos(context.Background(), alephiumClient, "w", node.address, ConsolidateOptions{
+		Groups:    4,
+		MaxInputs: 10,
+		GasBudget: budget,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result.Transactions))
+	assert.True(t, result.BudgetExhausted)
+	assert.Equal(t, budget.String(), result.GasFee.String())
+	assert.Equal(t, 16, len(node.utxos))
+
+	_, err = ConsolidateUtxos(context.Background(), alephiumClient, "w", "invalid", ConsolidateOptions{Groups: 4})
+	assert.NotNil(t, err)
+}
+
+func TestConsolidateUtxosDust(t *testing.T) {
+
+	node := newConsolidationNode(t, 0)
+	dust, _ := alephium.ALPHFromALPHString("0.0001")
+	small, _ := alephium.ALPHFromALPHString("0.0005")
+	one, _ := alephium.ALPHFromALPHString("1")
+	for i, amount := range []alephium.ALPH{dust, dust, dust, dust, dust, dust, small, small, small, small, one, one} {
+		node.utxos = append(node.utxos, alephium.Utxo{Ref: alephium.OutputRef{Hint: 1, Key: fmt.Sprintf("%064x", i)}, Amount: amount})
+	}
+	ts := httptest.NewServer(node)
+	defer ts.Close()
+	alephiumClient, err := alephium.NewClient(ts.URL, alephium.WithPollInterval(time.Millisecond))
+	assert.Nil(t, err)
+
+	result, err := ConsolidateUtxos(context.Background(), alephiumClient, "w", node.address, ConsolidateOptions{
+		Groups:    4,
+		MaxInputs: 3,
+	})
+	assert.Nil(t, err)
+	// the UTXOs worth less than the gas of their input are left alone,
+	// the smallest UTXOs kept don't cover the fee of a transaction on their own
+	assert.Equal(t, 3, len(result.Transactions))
+	assert.Equal(t, 8, result.Inputs)
+	assert.Equal(t, 7, len(node.utxos))
+	for _, tx := range node.submitted {
+		assert.True(t, tx.FixedOutputs[0].Amount.Cmp(codec.DustUtxoAmount) >= 0)
+	}
+}
+
+func TestConsolidateUtxosTokens(t *testing.T) {
+
+	node := newConsolidationNode(t, 2)
+	tokenId := strings.Repeat("ab", 32)
+	node.utxos[0].Tokens = []alephium.Token{{Id: tokenId, Amount: alephium.U256{Value: big.NewInt(2)}}}
+	node.utxos[1].Tokens = []alephium.Token{{Id: strings.ToUpper(tokenId), Amount: alephium.U256{Value: big.NewInt(3)}}}
+	ts := httptest.NewServer(node)
+	defer ts.Close()
+	alephiumClient, err := alephium.NewClient(ts.URL, alephium.WithPollInterval(time.Millisecond))
+	assert.Nil(t, err)
+
+	_, err = ConsolidateUtxos(context.Background(), alephiumClient, "w", node.address, ConsolidateOptions{Groups: 4})
+	assert.Nil(t, err)
+	// the same token, whatever the case of its id, is merged
+	assert.Equal(t, 1, len(node.submitted))
+	tokens := node.submitted[0].FixedOutputs[0].Tokens
+	assert.Equal(t, 1, len(tokens))
+	assert.Equal(t, "5", tokens[0].Amount.String())
+}
@@ -0,0 +1,56 @@
+package coinselect
+
+import (
+	"github.com/touilleio/alephium-go-client"
+	"sync"
+)
+
+// Reservations keeps track of the UTXOs selected by transactions not confirmed yet, so that concurrent
+// selections from the same address never spend the same UTXOs
+type Reservations struct {
+	mu       sync.Mutex
+	reserved map[alephium.OutputRef]bool
+}
+
+// NewReservations creates an empty set of reservations
+func NewReservations() *Reservations {
+	return &Reservations{reserved: make(map[alephium.OutputRef]bool)}
+}
+
+// Select is like Select, skipping the reserved UTXOs and reserving the ones selected
+func (r *Reservations) Select(utxos []alephium.Utxo, target alephium.ALPH, options Options) (Selection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exclude := options.Exclude
+	options.Exclude = func(ref alephium.OutputRef) bool {
+		return r.reserved[ref] || (exclude != nil && exclude(ref))
+	}
+	selection, err := Select(utxos, target, options)
+	if err != nil {
+		return selection, err
+	}
+	for _, utxo := range selection.Utxos {
+		r.reserved[utxo.Ref] = true
+	}
+	return selection, nil
+}
+
+// Release makes the UTXOs of the selection available again, once its transaction is confirmed
+// (the UTXOs are then spent) or abandoned
+func (r *Reservations) Release(selection Selection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, utxo := range selection.Utxos {
+		delete(r.reserved, utxo.Ref)
+	}
+}
+
+// Reserved is true if the UTXO is reserved
+func (r *Reservations) Reserved(ref alephium.OutputRef) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.reserved[ref]
+}
@@ -0,0 +1,416 @@
+// Package deposits detects the deposits to a set of addresses, across all the groups, for exchange
+// integrations.
+//
+// The Detector follows every chain of the blockflow with an alephium.ChainFollower, and the mempool
+// with GetUnconfirmedTransactions. Its cursors on the chains are persisted in a Store: when a reorg
+// disconnects a block, the deposits it contained are reverted.
+//
+// Events are emitted before the state is saved, so after a crash some of them may be emitted again:
+// they must be handled idempotently, by Deposit.Key.
+package deposits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/address"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultConfirmations    = 10
+	DefaultReorgDepth       = alephium.DefaultReorgDepth
+	DefaultMaxBlocksPerPoll = alephium.DefaultMaxBlocksPerPoll
+)
+
+// Status is the status of a Deposit
+type Status string
+
+const (
+	// StatusPending is a deposit whose transaction is in the mempool
+	StatusPending Status = "pending"
+	// StatusConfirmed is a deposit in a block, emitted again every time its confirmations change
+	StatusConfirmed Status = "confirmed"
+	// StatusFinal is a deposit with the required confirmations, not followed anymore
+	StatusFinal Status = "final"
+	// StatusReverted is a deposit whose block was replaced by a reorg. Its transaction may be confirmed again.
+	StatusReverted Status = "reverted"
+	// StatusDropped is a pending deposit whose transaction left the mempool without being confirmed
+	StatusDropped Status = "dropped"
+)
+
+// Deposit is an output of a transaction paying one of the watched addresses
+type Deposit struct {
+	Status      Status `json:"status"`
+	TxId        string `json:"txId"`
+	OutputIndex int    `json:"outputIndex"`
+	Address     string `json:"address"`
+	// Amount is the amount of ALPH deposited
+	Amount alephium.ALPH `json:"amount"`
+	// Tokens are the tokens deposited, if any
+	Tokens []alephium.Token `json:"tokens,omitempty"`
+	// LockTime is the timestamp, in milliseconds, until which the output can't be spent
+	LockTime  int64 `json:"lockTime"`
+	FromGroup int   `json:"fromGroup"`
+	ToGroup   int   `json:"toGroup"`
+	// BlockHash and Height are the block of the deposit, empty if pending
+	BlockHash string `json:"blockHash,omitempty"`
+	Height    int    `json:"height"`
+	// Confirmations is the number of blocks of the chain from the block of the deposit, 0 if pending
+	Confirmations int `json:"confirmations"`
+}
+
+// Key identifies the deposit, i.e. its output
+func (d Deposit) Key() string {
+	return fmt.Sprintf("%s:%d", d.TxId, d.OutputIndex)
+}
+
+// Config configures the Detector
+type Config struct {
+	// Addresses are the addresses watched, see also Detector.AddAddress
+	Addresses []string
+	// Groups is the number of groups of the clique, fetched from the node if 0
+	Groups int
+	// Confirmations is the number of confirmations from which a deposit is final, DefaultConfirmations if 0
+	Confirmations int
+	// ReorgDepth is the number of blocks checked for reorgs on every chain, DefaultReorgDepth if 0.
+	// It can't be lower than Confirmations.
+	ReorgDepth int
+	// StartHeight is the height of the first block processed on the chains without cursor,
+	// the block after the current one if 0
+	StartHeight int
+	// MaxBlocksPerPoll is the maximum number of blocks processed per chain by Poll, DefaultMaxBlocksPerPoll if 0
+	MaxBlocksPerPoll int
+	// PollInterval is the interval between two polls of Run, alephium.DefaultPollInterval if 0
+	PollInterval time.Duration
+	// OnDeposit, if set, is called with every event, from the polling loop
+	OnDeposit func(Deposit)
+	// Events, if set, receives every event. The polling loop blocks until they are received.
+	Events chan<- Deposit
+	// Log is the logger of the detector, nothing is logged if nil
+	Log alephium.Logger
+}
+
+// Detector emits the deposits to the watched addresses, see the package documentation
+type Detector struct {
+	client   *alephium.Client
+	store    Store
+	config   Config
+	log      alephium.Logger
+	mempool  *alephium.MempoolMonitor
+	follower *alephium.ChainFollower
+
+	// mu protects addresses and state, pollMu makes sure a single poll runs at a time
+	mu        sync.Mutex
+	pollMu    sync.Mutex
+	addresses map[string]bool
+	state     State
+	// pending are the deposits of the mempool, by transaction, and removed the transactions which left it
+	pending map[string][]Deposit
+	removed map[string]bool
+	// mempoolEvents and chainEvents are the events of the last poll of the mempool and of the chains
+	mempoolEvents []alephium.MempoolEvent
+	chainEvents   []alephium.ChainEvent
+}
+
+// New creates a detector, restoring its state from the store
+func New(client *alephium.Client, store Store, config Config) (*Detector, error) {
+	if config.Confirmations <= 0 {
+		config.Confirmations = DefaultConfirmations
+	}
+	if config.ReorgDepth <= 0 {
+		config.ReorgDepth = DefaultReorgDepth
+	}
+	if config.ReorgDepth < config.Confirmations {
+		return nil, fmt.Errorf("the reorg depth %d can't be lower than the confirmations %d", config.ReorgDepth, config.Confirmations)
+	}
+	if config.MaxBlocksPerPoll <= 0 {
+		config.MaxBlocksPerPoll = DefaultMaxBlocksPerPoll
+	}
+	log := config.Log
+	if log == nil {
+		log = alephium.NopLogger{}
+	}
+
+	state, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Detector{
+		client:    client,
+		store:     store,
+		config:    config,
+		log:       log,
+		addresses: make(map[string]bool),
+		state:     state,
+		pending:   make(map[string][]Deposit),
+		removed:   make(map[string]bool),
+	}
+	for _, addr := range config.Addresses {
+		if err := d.AddAddress(addr); err != nil {
+			return nil, err
+		}
+	}
+	d.mempool = client.NewMempoolMonitor(alephium.MempoolMonitorConfig{OnEvent: func(event alephium.MempoolEvent) {
+		d.mempoolEvents = append(d.mempoolEvents, event)
+	}})
+	d.follower = client.NewChainFollower(alephium.ChainFollowerConfig{
+		Groups:           config.Groups,
+		ReorgDepth:       config.ReorgDepth,
+		StartHeight:      config.StartHeight,
+		MaxBlocksPerPoll: config.MaxBlocksPerPoll,
+		FetchBlocks:      true,
+		Cursors:          state.Cursors,
+		OnEvent: func(event alephium.ChainEvent) {
+			d.chainEvents = append(d.chainEvents, event)
+		},
+	})
+	return d, nil
+}
+
+// AddAddress watches a new address, from the next poll
+func (d *Detector) AddAddress(addr string) error {
+	if err := address.Validate(addr); err != nil {
+		return fmt.Errorf("%w %q: %v", alephium.ErrInvalidAddress, addr, err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.addresses[addr] = true
+	return nil
+}
+
+// Deposits returns the deposits in a block, not final yet
+func (d *Detector) Deposits() []Deposit {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Deposit(nil), d.state.Deposits...)
+}
+
+// Run polls the node until the context is done, returning its error.
+// Failing polls are logged and retried at the next interval.
+func (d *Detector) Run(ctx context.Context) error {
+	interval := d.config.PollInterval
+	if interval <= 0 {
+		interval = alephium.DefaultPollInterval
+	}
+	for {
+		if err := d.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			d.log.Warnf("Failed to poll the deposits: %v", err)
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Poll processes the new blocks of every chain, then the mempool, and emits the deposits found
+func (d *Detector) Poll(ctx context.Context) error {
+	d.pollMu.Lock()
+	defer d.pollMu.Unlock()
+
+	d.chainEvents = nil
+	// the blocks (dis)connected before an error are processed anyway, as the cursors moved past them
+	followErr := d.follower.Poll(ctx)
+	cursors := d.follower.Cursors()
+
+	var events []Deposit
+	d.mu.Lock()
+	for _, event := range d.chainEvents {
+		if event.Kind == alephium.BlockDisconnected {
+			events = append(events, d.revert(event)...)
+		} else {
+			d.connect(event)
+		}
+	}
+	deposits := d.state.Deposits[:0]
+	for _, deposit := range d.state.Deposits {
+		cursor, ok := cursors[alephium.ChainKey(deposit.FromGroup, deposit.ToGroup)]
+		if ok {
+			confirmations := cursor.CurrentHeight - deposit.Height + 1
+			if confirmations != deposit.Confirmations {
+				deposit.Confirmations = confirmations
+				if confirmations >= d.config.Confirmations {
+					deposit.Status = StatusFinal
+					events = append(events, deposit)
+					continue
+				}
+				events = append(events, deposit)
+			}
+		}
+		deposits = append(deposits, deposit)
+	}
+	d.state.Deposits = deposits
+	d.state.Cursors = cursors
+	d.mu.Unlock()
+
+	if err := d.emit(ctx, events); err != nil {
+		return err
+	}
+	// the state is only modified by the poll
+	if err := d.store.Save(d.state); err != nil {
+		return err
+	}
+	if followErr != nil {
+		return followErr
+	}
+	return d.pollMempool(ctx)
+}
+
+// revert removes the deposits of the block disconnected, returning them reverted
+func (d *Detector) revert(event alephium.ChainEvent) []Deposit {
+	var reverted []Deposit
+	deposits := d.state.Deposits[:0]
+	for _, deposit := range d.state.Deposits {
+		if deposit.BlockHash == event.Hash {
+			deposit.Status = StatusReverted
+			reverted = append(reverted, deposit)
+			continue
+		}
+		deposits = append(deposits, deposit)
+	}
+	d.state.Deposits = deposits
+	return reverted
+}
+
+// connect adds the deposits of the block connected, confirming their pending deposits if any
+func (d *Detector) connect(event alephium.ChainEvent) {
+	for _, tx := range event.Block.Transactions {
+		for i, output := range tx.Outputs {
+			if !d.addresses[output.Address] {
+				continue
+			}
+			deposit := Deposit{
+				Status:      StatusConfirmed,
+				TxId:        tx.Id,
+				OutputIndex: i,
+				Address:     output.Address,
+				Amount:      output.Amount,
+				Tokens:      output.Tokens,
+				LockTime:    output.LockTime,
+				FromGroup:   event.FromGroup,
+				ToGroup:     event.ToGroup,
+				BlockHash:   event.Hash,
+				Height:      event.Height,
+			}
+			d.log.Debugf("Deposit %s of %s to %s in block %s", deposit.Key(), deposit.Amount, deposit.Address, event.Hash)
+			d.state.Deposits = append(d.state.Deposits, deposit)
+		}
+		delete(d.pending, tx.Id)
+		delete(d.removed, tx.Id)
+	}
+}
+
+// pollMempool emits the pending deposits of the transactions entering the mempool, and drops the ones
+// which left it at the previous poll and are unknown to the node
+func (d *Detector) pollMempool(ctx context.Context) error {
+	var events []Deposit
+
+	d.mu.Lock()
+	candidates := make([]Deposit, 0)
+	for txId, deposits := range d.pending {
+		if d.removed[txId] {
+			candidates = append(candidates, deposits[0])
+		}
+	}
+	d.mu.Unlock()
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].TxId < candidates[j].TxId })
+	// a transaction known to the node stays pending, e.g. in a block not processed yet by the follower
+	removed := make(map[string]bool)
+	dropped := make([]string, 0)
+	for _, candidate := range candidates {
+		status, err := d.client.GetTxStatusCtx(ctx, candidate.TxId, candidate.FromGroup, candidate.ToGroup)
+		if err != nil && !errors.Is(err, alephium.ErrNotFound) {
+			return err
+		}
+		if _, notFound := status.(alephium.TxNotFound); err == nil && !notFound {
+			removed[candidate.TxId] = true
+			continue
+		}
+		dropped = append(dropped, candidate.TxId)
+	}
+
+	d.mu.Lock()
+	for _, txId := range dropped {
+		for _, deposit := range d.pending[txId] {
+			deposit.Status = StatusDropped
+			events = append(events, deposit)
+		}
+		delete(d.pending, txId)
+	}
+	d.removed = removed
+	confirmed := make(map[string]bool)
+	for _, deposit := range d.state.Deposits {
+		confirmed[deposit.TxId] = true
+	}
+	d.mu.Unlock()
+	if err := d.emit(ctx, events); err != nil {
+		return err
+	}
+	events = nil
+
+	d.mempoolEvents = nil
+	if err := d.mempool.Poll(ctx); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	for _, event := range d.mempoolEvents {
+		tx := event.Transaction.Unsigned
+		if event.Kind == alephium.MempoolRemoved {
+			d.removed[tx.TxId] = true
+			continue
+		}
+		if confirmed[tx.TxId] {
+			continue
+		}
+		for i, output := range tx.FixedOutputs {
+			if !d.addresses[output.Address] {
+				continue
+			}
+			deposit := Deposit{
+				Status:      StatusPending,
+				TxId:        tx.TxId,
+				OutputIndex: i,
+				Address:     output.Address,
+				Amount:      output.Amount,
+				Tokens:      output.Tokens,
+				LockTime:    output.LockTime,
+				FromGroup:   event.FromGroup,
+				ToGroup:     event.ToGroup,
+			}
+			d.pending[tx.TxId] = append(d.pending[tx.TxId], deposit)
+			events = append(events, deposit)
+		}
+	}
+	d.mu.Unlock()
+
+	return d.emit(ctx, events)
+}
+
+// emit sends the events to the callback and the channel of the configuration
+func (d *Detector) emit(ctx context.Context, events []Deposit) error {
+	for _, event := range events {
+		if d.config.OnDeposit != nil {
+			d.config.OnDeposit(event)
+		}
+		if d.config.Events != nil {
+			select {
+			case d.config.Events <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package deposits
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/touilleio/alephium-go-client"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	watched = "1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi"
+	other   = "1Ambgi1jNRcBcdDUSfyrY2uQXdHpJs3zfc7Nmt6NcpBbL"
+)
+
+// fakeNode is a single group clique, with a single chain
+type fakeNode struct {
+	mu      sync.Mutex
+	chain   []string
+	blocks  map[string]alephium.BlockEntry
+	mempool []string
+	// confirmed are the transactions in blocks not served yet
+	confirmed map[string]bool
+}
+
+func (n *fakeNode) block(height int, hash string, txIds ...string) {
+	entry := alephium.BlockEntry{Hash: hash, Height: height}
+	for _, txId := range txIds {
+		amount, _ := alephium.ALPHFromALPHString("1")
+		entry.Transactions = append(entry.Transactions, alephium.Tx{Id: txId, Outputs: []alephium.Output{
+			{Amount: amount, Address: other},
+			{Amount: amount, Address: watched},
+		}})
+	}
+	for len(n.chain) <= height {
+		n.chain = append(n.chain, "")
+	}
+	n.chain[height] = hash
+	n.blocks[hash] = entry
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.URL.Path == "/blockflow/chains":
+		_ = json.NewEncoder(w).Encode(alephium.ChainInfo{CurrentHeight: len(n.chain) - 1})
+	case r.URL.Path == "/blockflow/hashes":
+		height, _ := strconv.Atoi(r.URL.Query().Get("height"))
+		hashes := alephium.HashesAtHeight{Headers: []string{}}
+		if height < len(n.chain) {
+			hashes.Headers = append(hashes.Headers, n.chain[height])
+		}
+		_ = json.NewEncoder(w).Encode(hashes)
+	case strings.HasPrefix(r.URL.Path, "/blockflow/blocks/"):
+		_ = json.NewEncoder(w).Encode(n.blocks[strings.TrimPrefix(r.URL.Path, "/blockflow/blocks/")])
+	case r.URL.Path == "/transactions/unconfirmed":
+		txs := make([]string, 0)
+		for _, txId := range n.mempool {
+			txs = append(txs, fmt.Sprintf(`{"unsigned":{"txId":"%s","inputs":[],"fixedOutputs":[
+				{"amount":"2000000000000000000","address":"%s","tokens":[]}]}}`, txId, watched))
+		}
+		_, _ = w.Write([]byte(`[{"fromGroup":0,"toGroup":0,"unconfirmedTransactions":[` + strings.Join(txs, ",") + `]}]`))
+	case r.URL.Path == "/transactions/status":
+		status := "tx-not-found"
+		if n.confirmed[r.URL.Query().Get("txId")] {
+			status = "confirmed"
+		}
+		_, _ = w.Write([]byte(`{"type":"` + status + `"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (n *fakeNode) update(f func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f()
+}
+
+func TestDetector(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "deposits")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	store := NewFileStore(filepath.Join(dir, "deposits.json"))
+
+	node := &fakeNode{blocks: make(map[string]alephium.BlockEntry)}
+	node.block(0, "genesis")
+	node.block(1, "h1")
+	node.block(2, "h2", "t1")
+	node.mempool = []string{"t2"}
+	ts := httptest.NewServer(node)
+	defer ts.Close()
+	client, err := alephium.NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	var events []string
+	config := Config{
+		Addresses:     []string{watched},
+		Groups:        1,
+		Confirmations: 2,
+		ReorgDepth:    5,
+		StartHeight:   1,
+		OnDeposit: func(deposit Deposit) {
+			events = append(events, fmt.Sprintf("%s %s %d", deposit.Status, deposit.Key(), deposit.Confirmations))
+		},
+	}
+	detector, err := New(client, store, config)
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	assert.Nil(t, detector.Poll(ctx))
+	assert.Equal(t, []string{"confirmed t1:1 1", "pending t2:0 0"}, events)
+	assert.Equal(t, watched, detector.Deposits()[0].Address)
+
+	// the block at height 2 is replaced by one with t2, a new transaction enters the mempool
+	node.update(func() {
+		node.block(2, "h2'", "t2")
+		node.block(3, "h3")
+		node.mempool = []string{"t3"}
+	})
+	events = nil
+	assert.Nil(t, detector.Poll(ctx))
+	assert.Equal(t, []string{"reverted t1:1 1", "final t2:1 2", "pending t3:0 0"}, events)
+	assert.Equal(t, 0, len(detector.Deposits()))
+
+	// t3 leaves the mempool in a block not served yet, then the block is replaced
+	node.update(func() {
+		node.mempool = nil
+		node.confirmed = map[string]bool{"t3": true}
+	})
+	events = nil
+	assert.Nil(t, detector.Poll(ctx))
+	assert.Nil(t, detector.Poll(ctx))
+	assert.Equal(t, 0, len(events))
+	node.update(func() {
+		node.confirmed = nil
+	})
+	assert.Nil(t, detector.Poll(ctx))
+	assert.Equal(t, []string{"dropped t3:0 0"}, events)
+
+	// the cursor is restored from the store
+	state, err := store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, state.Cursors["0-0"].Height)
+	assert.Equal(t, "h2'", state.Cursors["0-0"].Hashes[2])
+	node.update(func() {
+		node.block(4, "h4", "t4")
+	})
+	events = nil
+	detector, err = New(client, store, config)
+	assert.Nil(t, err)
+	assert.Nil(t, detector.Poll(ctx))
+	assert.Equal(t, []string{"confirmed t4:1 1"}, events)
+
+	_, err = New(client, store, Config{Addresses: []string{"invalid"}})
+	assert.NotNil(t, err)
+	_, err = New(client, store, Config{Confirmations: 10, ReorgDepth: 5})
+	assert.NotNil(t, err)
+}
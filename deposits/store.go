@@ -0,0 +1,56 @@
+package deposits
+
+import (
+	"encoding/json"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/internal/atomicfile"
+	"io/ioutil"
+	"os"
+)
+
+// State is the persisted state of the Detector
+type State struct {
+	// Cursors are the cursors of the chains, by alephium.ChainKey
+	Cursors map[string]alephium.ChainCursor `json:"cursors"`
+	// Deposits are the deposits in a block, not final yet
+	Deposits []Deposit `json:"deposits"`
+}
+
+// Store persists the state of the Detector. Save must be atomic: after a crash, Load returns either
+// the previous or the new state.
+type Store interface {
+	Load() (State, error)
+	Save(State) error
+}
+
+// FileStore stores the state as JSON in a local file
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a store persisting the state in the file at path
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load returns the persisted state, or an empty one if the file doesn't exist
+func (f *FileStore) Load() (State, error) {
+	var state State
+	b, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(b, &state)
+	return state, err
+}
+
+// Save writes the state atomically
+func (f *FileStore) Save(state State) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(f.path, b)
+}
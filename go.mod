@@ -3,10 +3,15 @@ module github.com/touilleio/alephium-go-client
 go 1.15
 
 require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1
 	github.com/dghubble/sling v1.3.0
+	github.com/docker/go-connections v0.4.0
 	github.com/sirupsen/logrus v1.7.0
 	github.com/sqooba/go-common v0.0.0-20210312063917-35b2ebfb97ab
 	github.com/stretchr/testify v1.7.0
 	github.com/testcontainers/testcontainers-go v0.10.0
+	github.com/tyler-smith/go-bip39 v1.1.0
 	github.com/willf/pad v0.0.0-20200313202418-172aa767f2a4
+	golang.org/x/crypto v0.0.0-20201002170205-7f63de1d35b0
+	lukechampine.com/blake3 v1.1.7
 )
@@ -0,0 +1,112 @@
+package hd
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HardenedOffset is added to the index of hardened children
+	HardenedOffset = uint32(0x80000000)
+	// CoinType is the BIP44 coin type of Alephium
+	CoinType = uint32(1234)
+)
+
+var masterKey = []byte("Bitcoin seed")
+
+// ExtendedKey is a BIP32 extended private key
+type ExtendedKey struct {
+	key       secp256k1.ModNScalar
+	chainCode []byte
+}
+
+// NewMasterKey computes the master key of the seed
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	mac := hmac.New(sha512.New, masterKey)
+	_, _ = mac.Write(seed)
+	i := mac.Sum(nil)
+
+	var key secp256k1.ModNScalar
+	if overflow := key.SetByteSlice(i[:32]); overflow || key.IsZero() {
+		return nil, fmt.Errorf("invalid master key, use another seed")
+	}
+	return &ExtendedKey{key: key, chainCode: i[32:]}, nil
+}
+
+// Child derives the child key at the given index, hardened if index >= HardenedOffset
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	data := make([]byte, 0, 37)
+	if index >= HardenedOffset {
+		b := k.key.Bytes()
+		data = append(append(data, 0), b[:]...)
+	} else {
+		data = append(data, k.publicKey()...)
+	}
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(data)-4:], index)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	_, _ = mac.Write(data)
+	i := mac.Sum(nil)
+
+	var child secp256k1.ModNScalar
+	if overflow := child.SetByteSlice(i[:32]); overflow {
+		return nil, fmt.Errorf("invalid child key at index %d, use the next one", index)
+	}
+	child.Add(&k.key)
+	if child.IsZero() {
+		return nil, fmt.Errorf("invalid child key at index %d, use the next one", index)
+	}
+	return &ExtendedKey{key: child, chainCode: i[32:]}, nil
+}
+
+// DerivePath derives the key at a path like m/44'/1234'/0'/0/0
+func (k *ExtendedKey) DerivePath(path string) (*ExtendedKey, error) {
+	segments := strings.Split(path, "/")
+	if len(segments) == 0 || segments[0] != "m" {
+		return nil, fmt.Errorf("invalid path %s, expected to start with m", path)
+	}
+	key := k
+	for _, segment := range segments[1:] {
+		offset := uint32(0)
+		if strings.HasSuffix(segment, "'") {
+			offset = HardenedOffset
+			segment = strings.TrimSuffix(segment, "'")
+		}
+		index, err := strconv.ParseUint(segment, 10, 32)
+		if err != nil || uint32(index) >= HardenedOffset {
+			return nil, fmt.Errorf("invalid path %s, bad index %s", path, segment)
+		}
+		key, err = key.Child(uint32(index) + offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// PrivateKey returns the hex encoded private key
+func (k *ExtendedKey) PrivateKey() string {
+	b := k.key.Bytes()
+	return hex.EncodeToString(b[:])
+}
+
+// PublicKey returns the hex encoded compressed public key
+func (k *ExtendedKey) PublicKey() string {
+	return hex.EncodeToString(k.publicKey())
+}
+
+func (k *ExtendedKey) publicKey() []byte {
+	return secp256k1.NewPrivateKey(&k.key).PubKey().SerializeCompressed()
+}
+
+// Path returns the BIP44 path of the address at the given index
+func Path(index uint32) string {
+	return fmt.Sprintf("m/44'/%d'/0'/0/%d", CoinType, index)
+}
@@ -0,0 +1,95 @@
+package hd
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"github.com/touilleio/alephium-go-client/signer"
+	"strings"
+	"testing"
+)
+
+func TestMnemonic(t *testing.T) {
+
+	for _, size := range []int{12, 24} {
+		mnemonic, err := NewMnemonic(size)
+		assert.Nil(t, err)
+		assert.Equal(t, size, len(strings.Fields(mnemonic)))
+		assert.Nil(t, ValidateMnemonic(mnemonic))
+	}
+	_, err := NewMnemonic(13)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, ValidateMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"))
+	// bad checksum
+	assert.NotNil(t, ValidateMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon"))
+	assert.NotNil(t, ValidateMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon alephium"))
+
+	// BIP39 test vector
+	seed, err := Seed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "TREZOR")
+	assert.Nil(t, err)
+	assert.Equal(t, "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04", hex.EncodeToString(seed))
+}
+
+func TestBIP32(t *testing.T) {
+
+	// BIP32 test vector 1
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterKey(seed)
+	assert.Nil(t, err)
+	assert.Equal(t, "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35", master.PrivateKey())
+	assert.Equal(t, "0339a36013301597daef41fbe593a02cc513d0b55527ec2df1050e2e8ff49c85c2", master.PublicKey())
+
+	key, err := master.DerivePath("m/0'")
+	assert.Nil(t, err)
+	assert.Equal(t, "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea", key.PrivateKey())
+
+	key, err = master.DerivePath("m/0'/1/2'/2/1000000000")
+	assert.Nil(t, err)
+	assert.Equal(t, "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8", key.PrivateKey())
+
+	_, err = master.DerivePath("0/1")
+	assert.NotNil(t, err)
+	_, err = master.DerivePath("m/x")
+	assert.NotNil(t, err)
+}
+
+func TestWallet(t *testing.T) {
+
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	wallet, err := NewWallet(mnemonic, "", 4)
+	assert.Nil(t, err)
+	withPassphrase, err := NewWallet(mnemonic, "passphrase", 4)
+	assert.Nil(t, err)
+
+	account, err := wallet.Account(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "m/44'/1234'/0'/0/0", account.Path)
+	other, err := withPassphrase.Account(0)
+	assert.Nil(t, err)
+	assert.NotEqual(t, account.Address, other.Address)
+
+	next, err := wallet.NextAccount(account)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), next.Index)
+	assert.NotEqual(t, account.Address, next.Address)
+
+	miners, err := wallet.MinerAccounts(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(miners))
+	for group, miner := range miners {
+		assert.Equal(t, group, miner.Group)
+	}
+
+	s, err := account.Signer()
+	assert.Nil(t, err)
+	assert.Equal(t, account.PublicKey, s.PublicKey())
+	hash := "bdaf9dc514ce7d34b6474b8ca10a3dfb93ba997cb9d5ff1ea724ebe2af48abe5"
+	signature, err := s.Sign(hash)
+	assert.Nil(t, err)
+	ok, err := signer.Verify(account.PublicKey, hash, signature)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	_, err = NewWallet(mnemonic, "", 0)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,70 @@
+// Package hd derives keys and addresses from a BIP39 mnemonic the same way the wallet of the node does,
+// along the BIP44 path m/44'/1234'/0'/0/index, so that a wallet can be restored and used without the node
+// ever holding the mnemonic.
+package hd
+
+import (
+	"fmt"
+	"github.com/tyler-smith/go-bip39"
+	"strings"
+)
+
+// MnemonicSizes are the number of words of the mnemonics accepted by the node
+var MnemonicSizes = []int{12, 15, 18, 21, 24}
+
+// NewMnemonic generates a random english mnemonic of the given number of words
+func NewMnemonic(size int) (string, error) {
+	bitSize, err := entropyBitSize(size)
+	if err != nil {
+		return "", err
+	}
+	entropy, err := bip39.NewEntropy(bitSize)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// ValidateMnemonic checks the words and the checksum of the mnemonic
+func ValidateMnemonic(mnemonic string) error {
+	if err := validateWords(mnemonic); err != nil {
+		return err
+	}
+	if _, err := bip39.EntropyFromMnemonic(strings.Join(strings.Fields(mnemonic), " ")); err != nil {
+		return fmt.Errorf("invalid mnemonic: %v", err)
+	}
+	return nil
+}
+
+// Seed computes the BIP39 seed of the mnemonic, the passphrase being the mnemonicPassphrase
+// given to CreateWallet or RestoreWallet, possibly empty.
+// Like the node, only the size and the words are checked, not the checksum, so that wallets
+// restored from a mnemonic like TestGenesisWalletMnemonics are supported.
+func Seed(mnemonic string, passphrase string) ([]byte, error) {
+	if err := validateWords(mnemonic); err != nil {
+		return nil, err
+	}
+	return bip39.NewSeed(strings.Join(strings.Fields(mnemonic), " "), passphrase), nil
+}
+
+func validateWords(mnemonic string) error {
+	words := strings.Fields(mnemonic)
+	if _, err := entropyBitSize(len(words)); err != nil {
+		return err
+	}
+	for _, word := range words {
+		if _, ok := bip39.GetWordIndex(word); !ok {
+			return fmt.Errorf("invalid mnemonic, unknown word %q", word)
+		}
+	}
+	return nil
+}
+
+func entropyBitSize(size int) (int, error) {
+	for _, s := range MnemonicSizes {
+		if s == size {
+			return size * 32 / 3, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid mnemonic size %d, expected one of %v", size, MnemonicSizes)
+}
@@ -0,0 +1,89 @@
+package hd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/signer"
+)
+
+// Account is a key pair derived from the mnemonic, with its address
+type Account struct {
+	Index      uint32
+	Path       string
+	PrivateKey string
+	PublicKey  string
+	Address    string
+	// Group is the group of the address, given the number of groups of the wallet
+	Group int
+}
+
+// Signer returns a signer using the private key of the account
+func (a Account) Signer() (*signer.Signer, error) {
+	return signer.New(a.PrivateKey)
+}
+
+// Wallet derives the accounts of a mnemonic
+type Wallet struct {
+	master *ExtendedKey
+	groups int
+}
+
+// NewWallet creates a wallet from a mnemonic and its passphrase, possibly empty, for a clique
+// with the given number of groups
+func NewWallet(mnemonic string, passphrase string, groups int) (*Wallet, error) {
+	if groups <= 0 {
+		return nil, fmt.Errorf("invalid number of groups %d", groups)
+	}
+	seed, err := Seed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	return &Wallet{master: master, groups: groups}, nil
+}
+
+// Account derives the account at the given index
+func (w *Wallet) Account(index uint32) (Account, error) {
+	path := Path(index)
+	key, err := w.master.DerivePath(path)
+	if err != nil {
+		return Account{}, err
+	}
+	publicKey := key.publicKey()
+	p2pkh := address.NewP2PKH(publicKey)
+	return Account{
+		Index:      index,
+		Path:       path,
+		PrivateKey: key.PrivateKey(),
+		PublicKey:  hex.EncodeToString(publicKey),
+		Address:    p2pkh.String(),
+		Group:      p2pkh.Group(w.groups),
+	}, nil
+}
+
+// NextAccount derives the account following the given one, like DeriveNextAddress
+func (w *Wallet) NextAccount(current Account) (Account, error) {
+	return w.Account(current.Index + 1)
+}
+
+// MinerAccounts derives an account for every group, the first one of every group starting
+// after the given index, like DeriveNextMinerAddresses. Use 0 for a new miner wallet.
+func (w *Wallet) MinerAccounts(from uint32) ([]Account, error) {
+	accounts := make([]Account, w.groups)
+	found := 0
+	for index := from; found < w.groups; index++ {
+		account, err := w.Account(index)
+		if err != nil {
+			return nil, err
+		}
+		if accounts[account.Group].Address == "" {
+			accounts[account.Group] = account
+			found++
+		}
+	}
+	return accounts, nil
+}
@@ -0,0 +1,212 @@
+// Package miner is a reference CPU miner, fetching block candidates from the node
+// for every chain and searching nonces on several goroutines.
+// It is meant for tests against a devnet, e.g. configured with user-dev-standalone.conf,
+// not for mining on the mainnet.
+package miner
+
+import (
+	"context"
+	"github.com/touilleio/alephium-go-client"
+	"math/big"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultRefreshInterval = 10 * time.Second
+	// nonces checked by a worker before looking for a refresh
+	batchSize = 1024
+)
+
+// Config configures the Miner. All the fields are optional.
+type Config struct {
+	// Workers is the number of goroutines searching nonces, runtime.NumCPU() if 0
+	Workers int
+	// Groups is the number of groups of the clique, fetched from the node if 0
+	Groups int
+	// RefreshInterval is the interval after which the candidates are fetched again,
+	// DefaultRefreshInterval if 0
+	RefreshInterval time.Duration
+	// Log is the logger of the miner, nothing is logged if nil
+	Log alephium.Logger
+}
+
+// Stats are the statistics of the miner since it started
+type Stats struct {
+	Hashes   uint64
+	Blocks   uint64
+	Rejected uint64
+	HashRate float64
+}
+
+// Miner mines blocks on every chain of the clique
+type Miner struct {
+	client *alephium.Client
+	config Config
+	log    alephium.Logger
+
+	hashes   uint64
+	blocks   uint64
+	rejected uint64
+	started  time.Time
+}
+
+type solution struct {
+	job         *Job
+	nonce       *big.Int
+	miningCount uint64
+}
+
+// New creates a miner fetching candidates and submitting solutions through the client
+func New(client *alephium.Client, config Config) *Miner {
+	if config.Workers <= 0 {
+		config.Workers = runtime.NumCPU()
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = DefaultRefreshInterval
+	}
+	log := config.Log
+	if log == nil {
+		log = alephium.NopLogger{}
+	}
+	return &Miner{
+		client: client,
+		config: config,
+		log:    log,
+	}
+}
+
+// Run mines until the context is done
+func (m *Miner) Run(ctx context.Context) error {
+	groups := m.config.Groups
+	if groups <= 0 {
+		selfClique, err := m.client.GetSelfCliqueInfosCtx(ctx)
+		if err != nil {
+			return err
+		}
+		groups = selfClique.Groups
+	}
+	m.started = time.Now()
+
+	for {
+		jobs := m.fetchJobs(ctx, groups)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if len(jobs) == 0 {
+			m.log.Warnf("No block candidate available, retrying in %s", m.config.RefreshInterval)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(m.config.RefreshInterval):
+			}
+			continue
+		}
+
+		found := m.mine(ctx, jobs)
+		if found == nil {
+			continue
+		}
+		err := m.client.SubmitBlockSolutionCtx(ctx, found.job.Solution(found.nonce, found.miningCount))
+		if err != nil {
+			atomic.AddUint64(&m.rejected, 1)
+			m.log.Warnf("Block solution for chain %d -> %d rejected: %v", found.job.FromGroup, found.job.ToGroup, err)
+		} else {
+			atomic.AddUint64(&m.blocks, 1)
+			m.log.Infof("Block mined on chain %d -> %d with nonce %s", found.job.FromGroup, found.job.ToGroup, found.nonce)
+		}
+	}
+}
+
+// fetchJobs fetches the candidates of every chain, skipping the failing ones
+func (m *Miner) fetchJobs(ctx context.Context, groups int) []*Job {
+	jobs := make([]*Job, 0, groups*groups)
+	for fromGroup := 0; fromGroup < groups; fromGroup++ {
+		for toGroup := 0; toGroup < groups; toGroup++ {
+			candidate, err := m.client.GetBlockCandidateCtx(ctx, fromGroup, toGroup)
+			if err != nil {
+				m.log.Debugf("Failed to get block candidate for chain %d -> %d: %v", fromGroup, toGroup, err)
+				continue
+			}
+			job, err := NewJob(fromGroup, toGroup, groups, candidate)
+			if err != nil {
+				m.log.Warnf("Invalid block candidate for chain %d -> %d: %v", fromGroup, toGroup, err)
+				continue
+			}
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// mine searches a nonce solving any of the jobs, until the refresh interval elapses.
+func (m *Miner) mine(ctx context.Context, jobs []*Job) *solution {
+	mineCtx, cancel := context.WithTimeout(ctx, m.config.RefreshInterval)
+	defer cancel()
+
+	solutions := make(chan *solution, 1)
+	var miningCount uint64
+	var wg sync.WaitGroup
+	for w := 0; w < m.config.Workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			nonce := new(big.Int)
+			start := rand.Uint64()
+			for i := uint64(0); ; i += batchSize {
+				if mineCtx.Err() != nil {
+					return
+				}
+				job := jobs[(w+int(i/batchSize))%len(jobs)]
+				for n := start + i; n < start+i+batchSize; n++ {
+					nonce.SetUint64(n)
+					if job.Check(job.Hash(nonce), job.Target) {
+						count := atomic.AddUint64(&miningCount, n-start-i+1)
+						atomic.AddUint64(&m.hashes, n-start-i+1)
+						select {
+						case solutions <- &solution{job: job, nonce: new(big.Int).Set(nonce), miningCount: count}:
+							cancel()
+						default:
+						}
+						return
+					}
+				}
+				atomic.AddUint64(&miningCount, batchSize)
+				atomic.AddUint64(&m.hashes, batchSize)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	select {
+	case found := <-solutions:
+		return found
+	default:
+		return nil
+	}
+}
+
+// Stats returns the statistics of the miner since it started
+func (m *Miner) Stats() Stats {
+	return Stats{
+		Hashes:   atomic.LoadUint64(&m.hashes),
+		Blocks:   atomic.LoadUint64(&m.blocks),
+		Rejected: atomic.LoadUint64(&m.rejected),
+		HashRate: m.HashRate(),
+	}
+}
+
+// HashRate returns the average number of hashes per second since the miner started
+func (m *Miner) HashRate() float64 {
+	if m.started.IsZero() {
+		return 0
+	}
+	elapsed := time.Since(m.started).Seconds()
+	if elapsed == 0 {
+		return 0
+	}
+	return float64(atomic.LoadUint64(&m.hashes)) / elapsed
+}
@@ -0,0 +1,92 @@
+package miner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/touilleio/alephium-go-client"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testHash = "bdaf9dc514ce7d34b6474b8ca10a3dfb93ba997cb9d5ff1ea724ebe2af48abe5"
+
+func TestTarget(t *testing.T) {
+	target, err := DecodeTarget("1a400000")
+	assert.Nil(t, err)
+	expected := new(big.Int).Lsh(big.NewInt(0x400000), 8*23)
+	assert.Equal(t, 0, expected.Cmp(target))
+	assert.Equal(t, "1a400000", EncodeTarget(target))
+
+	target, err = DecodeTarget("02008000")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0x80), target.Int64())
+	assert.Equal(t, "01800000", EncodeTarget(target))
+
+	_, err = DecodeTarget("1a40")
+	assert.NotNil(t, err)
+}
+
+func TestMiner(t *testing.T) {
+
+	var mu sync.Mutex
+	var solutions []alephium.BlockSolution
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/infos/self-clique":
+			_, _ = w.Write([]byte(`{"synced":true,"groups":2}`))
+		case "/miners/block-candidate":
+			_, _ = fmt.Fprintf(w, `{"deps":["%s","%s"],"depStateHash":"%s","target":"20010000","blockTs":%d,"txsHash":"%s","transactions":[]}`,
+				testHash, testHash, testHash, time.Now().UnixNano()/1e6, testHash)
+		case "/miners/new-block":
+			var solution alephium.BlockSolution
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&solution))
+			mu.Lock()
+			solutions = append(solutions, solution)
+			mu.Unlock()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := alephium.NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	miner := New(client, Config{Workers: 2, RefreshInterval: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	err = miner.Run(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	stats := miner.Stats()
+	assert.True(t, stats.Blocks > 0)
+	assert.True(t, stats.Hashes >= stats.Blocks)
+	assert.True(t, stats.HashRate > 0)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, int(stats.Blocks), len(solutions))
+	for _, solution := range solutions {
+		candidate := alephium.BlockCandidate{
+			Deps:         solution.BlockDeps,
+			DepStateHash: solution.DepStateHash,
+			Target:       solution.Target,
+			BlockTs:      solution.Timestamp,
+			TxsHash:      solution.TxsHash,
+		}
+		job, err := NewJob(solution.FromGroup, solution.ToGroup, 2, candidate)
+		assert.Nil(t, err)
+		hash := job.Hash(solution.Nonce.Value)
+		assert.True(t, job.Check(hash, job.Target))
+		fromGroup, toGroup := ChainIndex(hash, 2)
+		assert.Equal(t, solution.FromGroup, fromGroup)
+		assert.Equal(t, solution.ToGroup, toGroup)
+	}
+}
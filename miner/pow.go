@@ -0,0 +1,170 @@
+package miner
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/serde"
+	"lukechampine.com/blake3"
+	"math/big"
+)
+
+// MaxNonce is the largest nonce of a header, encoded as a U256
+var MaxNonce = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// Job is a block candidate of a given chain, ready to be mined
+type Job struct {
+	FromGroup int
+	ToGroup   int
+	Groups    int
+	Candidate alephium.BlockCandidate
+	Target    *big.Int
+
+	// headerPrefix is the serialized header, without the nonce
+	headerPrefix []byte
+}
+
+// NewJob prepares the candidate of the chain fromGroup -> toGroup to be mined,
+// groups being the number of groups of the clique.
+//
+// The header is serialized as the node does: deps, dep state hash, txs hash,
+// timestamp, target and nonce, and is hashed twice with blake3.
+func NewJob(fromGroup int, toGroup int, groups int, candidate alephium.BlockCandidate) (*Job, error) {
+	target, err := DecodeTarget(candidate.Target)
+	if err != nil {
+		return nil, err
+	}
+	compactTarget, err := hex.DecodeString(candidate.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := serde.EncodeI32(int32(len(candidate.Deps)))
+	for _, dep := range candidate.Deps {
+		b, err := decodeHash(dep)
+		if err != nil {
+			return nil, fmt.Errorf("dep %s: %v", dep, err)
+		}
+		prefix = append(prefix, b...)
+	}
+	for _, h := range []string{candidate.DepStateHash, candidate.TxsHash} {
+		b, err := decodeHash(h)
+		if err != nil {
+			return nil, fmt.Errorf("hash %s: %v", h, err)
+		}
+		prefix = append(prefix, b...)
+	}
+	ts := uint64(candidate.BlockTs)
+	prefix = append(prefix, byte(ts>>56), byte(ts>>48), byte(ts>>40), byte(ts>>32), byte(ts>>24), byte(ts>>16), byte(ts>>8), byte(ts))
+	prefix = append(prefix, serde.EncodeBytes(compactTarget)...)
+
+	return &Job{
+		FromGroup:    fromGroup,
+		ToGroup:      toGroup,
+		Groups:       groups,
+		Candidate:    candidate,
+		Target:       target,
+		headerPrefix: prefix,
+	}, nil
+}
+
+// Hash computes the proof of work hash of the header with the given nonce
+func (j *Job) Hash(nonce *big.Int) [32]byte {
+	return HashHeader(j.headerPrefix, nonce)
+}
+
+// HeaderPrefix returns the serialized header without its nonce, e.g. to be sent to remote workers
+func (j *Job) HeaderPrefix() []byte {
+	return j.headerPrefix
+}
+
+// HashHeader computes the proof of work hash of a serialized header without its nonce, with the given nonce
+func HashHeader(headerPrefix []byte, nonce *big.Int) [32]byte {
+	header := make([]byte, 0, len(headerPrefix)+33)
+	header = append(header, headerPrefix...)
+	header = append(header, serde.EncodeU256(nonce)...)
+	first := blake3.Sum256(header)
+	return blake3.Sum256(first[:])
+}
+
+// Check returns true if the hash solves the job with the given target,
+// i.e. it is below the target and belongs to the chain of the job.
+func (j *Job) Check(hash [32]byte, target *big.Int) bool {
+	if !CheckTarget(hash, target) {
+		return false
+	}
+	fromGroup, toGroup := ChainIndex(hash, j.Groups)
+	return fromGroup == j.FromGroup && toGroup == j.ToGroup
+}
+
+// Solution builds the block solution to be submitted to the node
+func (j *Job) Solution(nonce *big.Int, miningCount uint64) alephium.BlockSolution {
+	return alephium.BlockSolution{
+		BlockDeps:    j.Candidate.Deps,
+		DepStateHash: j.Candidate.DepStateHash,
+		Timestamp:    j.Candidate.BlockTs,
+		FromGroup:    j.FromGroup,
+		ToGroup:      j.ToGroup,
+		MiningCount:  alephium.U256{Value: new(big.Int).SetUint64(miningCount)},
+		Target:       j.Candidate.Target,
+		Nonce:        alephium.U256{Value: new(big.Int).Set(nonce)},
+		TxsHash:      j.Candidate.TxsHash,
+		Transactions: j.Candidate.Transactions,
+	}
+}
+
+// DecodeTarget decodes the compact representation of a target, e.g. 1a400000,
+// made of a 1 byte size and a 3 bytes mantissa.
+func DecodeTarget(compact string) (*big.Int, error) {
+	b, err := hex.DecodeString(compact)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != 4 {
+		return nil, fmt.Errorf("invalid target %s", compact)
+	}
+	size := int(b[0])
+	mantissa := new(big.Int).SetBytes(b[1:])
+	if size <= 3 {
+		return mantissa.Rsh(mantissa, uint(8*(3-size))), nil
+	}
+	return mantissa.Lsh(mantissa, uint(8*(size-3))), nil
+}
+
+// EncodeTarget is the inverse of DecodeTarget, truncating the target to its 3 most significant bytes
+func EncodeTarget(target *big.Int) string {
+	size := len(target.Bytes())
+	var mantissa *big.Int
+	if size <= 3 {
+		mantissa = new(big.Int).Lsh(target, uint(8*(3-size)))
+	} else {
+		mantissa = new(big.Int).Rsh(target, uint(8*(size-3)))
+	}
+	compact := make([]byte, 4)
+	compact[0] = byte(size)
+	mantissa.FillBytes(compact[1:])
+	return hex.EncodeToString(compact)
+}
+
+// CheckTarget returns true if the hash, as a big-endian integer, is lower than or equal to the target
+func CheckTarget(hash [32]byte, target *big.Int) bool {
+	return new(big.Int).SetBytes(hash[:]).Cmp(target) <= 0
+}
+
+// ChainIndex returns the chain a hash belongs to, based on its last 2 bytes
+func ChainIndex(hash [32]byte, groups int) (int, int) {
+	bigIndex := int(hash[30])<<8 | int(hash[31])
+	index := bigIndex % (groups * groups)
+	return index / groups, index % groups
+}
+
+func decodeHash(h string) ([]byte, error) {
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != 32 {
+		return nil, fmt.Errorf("expected 32 bytes, got %d", len(b))
+	}
+	return b, nil
+}
@@ -0,0 +1,579 @@
+// Package payout computes what the miners of a pool are owed, under PPLNS or PPS, and pays them
+// with multi-destination transactions signed by a wallet of the node.
+//
+// Every payment goes through the states pending, signed, submitted and confirmed, each of them
+// persisted in a Store before moving on. The transaction is signed, and its id known, before being
+// submitted, so that after a crash the Engine checks its status and submits the very same transaction
+// again if needed, instead of building a new one. This is why the wallet Transfer endpoint, which signs
+// and submits at once, is not used.
+package payout
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/codec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxDestinations = 20
+	// DefaultShareSaveInterval is the minimal interval between two saves of the state by Engine.AddShare
+	DefaultShareSaveInterval = 10 * time.Second
+)
+
+// PaymentStatus is the status of a Payment
+type PaymentStatus string
+
+const (
+	// PaymentPending is a payment whose transaction is not built yet
+	PaymentPending PaymentStatus = "pending"
+	// PaymentSigned is a payment whose transaction is signed, but maybe not submitted yet
+	PaymentSigned PaymentStatus = "signed"
+	// PaymentSubmitted is a payment whose transaction was accepted by the node
+	PaymentSubmitted PaymentStatus = "submitted"
+	// PaymentConfirmed is a payment whose transaction is confirmed
+	PaymentConfirmed PaymentStatus = "confirmed"
+	// PaymentFailed is a payment whose transaction couldn't be built or was rejected by the node, see Engine.Cancel
+	PaymentFailed PaymentStatus = "failed"
+)
+
+// Payment is a transaction paying several miners
+type Payment struct {
+	Id           int                               `json:"id"`
+	Status       PaymentStatus                     `json:"status"`
+	Destinations []alephium.TransactionDestination `json:"destinations"`
+	// Debited is what was debited from the balances, i.e. the amounts plus the transaction fee
+	Debited    map[string]alephium.ALPH `json:"debited"`
+	UnsignedTx string                   `json:"unsignedTx,omitempty"`
+	TxId       string                   `json:"txId,omitempty"`
+	Signature  string                   `json:"signature,omitempty"`
+	FromGroup  int                      `json:"fromGroup"`
+	ToGroup    int                      `json:"toGroup"`
+	CreatedAt  time.Time                `json:"createdAt"`
+	Error      string                   `json:"error,omitempty"`
+}
+
+// Config configures the Engine
+type Config struct {
+	// Scheme computes the credits of the miners, PPLNS or PPS
+	Scheme Scheme
+	// WalletName is the name of the wallet paying the miners
+	WalletName string
+	// PublicKey is the public key of the active address of the wallet
+	PublicKey string
+	// PoolFee is the fraction of every credit kept by the pool, e.g. 0.01 for 1%
+	PoolFee float64
+	// MinPayout is the balance from which a miner is paid
+	MinPayout alephium.ALPH
+	// TransactionFee is deducted from the amount paid to every miner
+	TransactionFee alephium.ALPH
+	// MaxDestinations is the maximum number of miners paid by a transaction, DefaultMaxDestinations if 0
+	MaxDestinations int
+	// ShareSaveInterval is the minimal interval between two saves of the state by AddShare,
+	// DefaultShareSaveInterval if 0. The shares added since the last save are lost on a crash, see Engine.Flush.
+	ShareSaveInterval time.Duration
+	// Log is the logger of the engine, nothing is logged if nil
+	Log alephium.Logger
+}
+
+// Engine credits shares and blocks to the miners, and pays them
+type Engine struct {
+	client *alephium.Client
+	store  Store
+	config Config
+	log    alephium.Logger
+
+	// mu protects state, payoutMu makes sure a single payout runs at a time
+	mu       sync.Mutex
+	payoutMu sync.Mutex
+	state    State
+	// dirty is true if shares were added since savedAt
+	dirty   bool
+	savedAt time.Time
+}
+
+// NewEngine creates an engine, restoring its state from the store
+func NewEngine(client *alephium.Client, store Store, config Config) (*Engine, error) {
+	if config.Scheme == nil {
+		return nil, fmt.Errorf("a payout scheme is required")
+	}
+	if config.WalletName == "" || config.PublicKey == "" {
+		return nil, fmt.Errorf("the wallet name and the public key of the paying address are required")
+	}
+	if config.PoolFee < 0 || config.PoolFee >= 1 {
+		return nil, fmt.Errorf("the pool fee must be in [0, 1), got %f", config.PoolFee)
+	}
+	if config.MinPayout.Amount == nil {
+		config.MinPayout = zero()
+	}
+	if config.TransactionFee.Amount == nil {
+		config.TransactionFee = zero()
+	}
+	if config.MaxDestinations <= 0 {
+		config.MaxDestinations = DefaultMaxDestinations
+	}
+	if config.ShareSaveInterval <= 0 {
+		config.ShareSaveInterval = DefaultShareSaveInterval
+	}
+	log := config.Log
+	if log == nil {
+		log = alephium.NopLogger{}
+	}
+
+	state, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	if state.Balances == nil {
+		state.Balances = make(map[string]alephium.ALPH)
+	}
+	if state.Blocks == nil {
+		state.Blocks = make(map[string]Block)
+	}
+
+	return &Engine{
+		client:  client,
+		store:   store,
+		config:  config,
+		log:     log,
+		state:   state,
+		savedAt: time.Now(),
+	}, nil
+}
+
+// AddShare records a share, to be used as pool.Config.OnShare. The state is saved at most every
+// ShareSaveInterval, or with the next block or payment.
+func (e *Engine) AddShare(share Share) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.state.Shares = append(e.state.Shares, share)
+	e.trimShares()
+	e.credit(e.config.Scheme.CreditShare(share))
+	e.dirty = true
+	if time.Since(e.savedAt) < e.config.ShareSaveInterval {
+		return nil
+	}
+	return e.save()
+}
+
+// Flush saves the shares added since the last save, to be called before stopping the engine
+func (e *Engine) Flush() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.dirty {
+		return nil
+	}
+	return e.save()
+}
+
+// CreditBlock credits the reward of a block to the miners. A block is credited only once.
+func (e *Engine) CreditBlock(block Block) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.state.Blocks[block.Hash]; ok {
+		e.log.Debugf("Block %s already credited", block.Hash)
+		return nil
+	}
+	e.credit(e.config.Scheme.CreditBlock(block, e.state.Shares))
+	e.state.Blocks[block.Hash] = block
+	return e.save()
+}
+
+// ProcessBlock reads the reward of a block from its coinbase transaction, and credits it
+func (e *Engine) ProcessBlock(ctx context.Context, hash string) error {
+	e.mu.Lock()
+	_, ok := e.state.Blocks[hash]
+	e.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	entry, err := e.client.GetBlockflowByHashCtx(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if len(entry.Transactions) == 0 {
+		return fmt.Errorf("block %s has no coinbase transaction", hash)
+	}
+	reward := zero()
+	// the coinbase transaction is the last one of the block
+	for _, output := range entry.Transactions[len(entry.Transactions)-1].Outputs {
+		reward = reward.Add(output.Amount)
+	}
+	return e.CreditBlock(Block{
+		Hash:      hash,
+		Reward:    reward,
+		Timestamp: time.Unix(0, entry.Timestamp*int64(time.Millisecond)),
+	})
+}
+
+// Balances returns what is owed to every miner, payments in progress excluded
+func (e *Engine) Balances() map[string]alephium.ALPH {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	balances := make(map[string]alephium.ALPH, len(e.state.Balances))
+	for address, balance := range e.state.Balances {
+		balances[address] = balance
+	}
+	return balances
+}
+
+// Payments returns all the payments, oldest first
+func (e *Engine) Payments() []Payment {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	payments := make([]Payment, len(e.state.Payments))
+	copy(payments, e.state.Payments)
+	return payments
+}
+
+// Cancel credits back the balances debited by a failed payment, or by a pending one, whose transaction
+// is not signed yet. It waits for a running Payout.
+//
+// A failed payment whose transaction was signed is cancelled only if the node doesn't know the transaction,
+// and at least one of its inputs is spent, so that it can never be confirmed: this way a miner is never
+// paid twice. The inputs can be spent with a new transaction, e.g. a consolidation, if needed.
+func (e *Engine) Cancel(id int) error {
+	return e.CancelCtx(context.Background(), id)
+}
+
+// CancelCtx is like Cancel, with a context
+func (e *Engine) CancelCtx(ctx context.Context, id int) error {
+	e.payoutMu.Lock()
+	defer e.payoutMu.Unlock()
+
+	payment := e.payment(id)
+	if payment.Status == PaymentFailed && payment.TxId != "" {
+		if err := e.checkUnconfirmable(ctx, payment); err != nil {
+			return err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, payment := range e.state.Payments {
+		if payment.Id != id {
+			continue
+		}
+		if payment.Status != PaymentFailed && payment.Status != PaymentPending {
+			return fmt.Errorf("payment %d is %s, only failed and pending payments can be cancelled", id, payment.Status)
+		}
+		e.addBalances(payment.Debited)
+		e.state.Payments = append(e.state.Payments[:i], e.state.Payments[i+1:]...)
+		return e.save()
+	}
+	return fmt.Errorf("payment %d not found", id)
+}
+
+// Payout completes the payments left over by a previous run, then pays every miner whose balance
+// reached MinPayout, and returns the payments confirmed. A payment failing with a transient error doesn't
+// stop the others, it is retried by the next Payout, and the first such error is returned.
+func (e *Engine) Payout(ctx context.Context) ([]Payment, error) {
+	e.payoutMu.Lock()
+	defer e.payoutMu.Unlock()
+
+	ids := make([]int, 0)
+	e.mu.Lock()
+	for _, payment := range e.state.Payments {
+		if payment.Status != PaymentConfirmed && payment.Status != PaymentFailed {
+			ids = append(ids, payment.Id)
+		}
+	}
+	newIds, err := e.createPayments()
+	e.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	ids = append(ids, newIds...)
+
+	confirmed := make([]Payment, 0, len(ids))
+	var firstErr error
+	for _, id := range ids {
+		payment, err := e.execute(ctx, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return confirmed, ctx.Err()
+			}
+			e.log.Warnf("Payment %d: %v", id, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("payment %d: %w", id, err)
+			}
+			continue
+		}
+		if payment.Status == PaymentConfirmed {
+			confirmed = append(confirmed, payment)
+		}
+	}
+	return confirmed, firstErr
+}
+
+// checkUnconfirmable returns an error unless the transaction of the payment is unknown to the node,
+// with an input spent. A transaction found is followed again by the next Payout.
+func (e *Engine) checkUnconfirmable(ctx context.Context, payment Payment) error {
+	status, err := e.client.GetTxStatusCtx(ctx, payment.TxId, payment.FromGroup, payment.ToGroup)
+	if err != nil && !errors.Is(err, alephium.ErrNotFound) {
+		return err
+	}
+	switch status.(type) {
+	case alephium.Confirmed:
+		if err := e.update(payment.Id, func(p *Payment) { p.Status = PaymentConfirmed }); err != nil {
+			return err
+		}
+		return fmt.Errorf("payment %d, tx %s, is confirmed", payment.Id, payment.TxId)
+	case alephium.MemPooled:
+		if err := e.update(payment.Id, func(p *Payment) { p.Status = PaymentSubmitted }); err != nil {
+			return err
+		}
+		return fmt.Errorf("payment %d, tx %s, is in the mempool", payment.Id, payment.TxId)
+	}
+
+	tx, err := codec.DecodeUnsignedTxHex(payment.UnsignedTx)
+	if err != nil {
+		return fmt.Errorf("payment %d: invalid transaction: %w", payment.Id, err)
+	}
+	publicKey, err := hex.DecodeString(e.config.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key %s: %v", e.config.PublicKey, err)
+	}
+	from := address.NewP2PKH(publicKey).String()
+	balance, err := e.client.GetAddressBalanceCtx(ctx, from, 0)
+	if err != nil {
+		return err
+	}
+	utxos, err := e.client.GetAddressUtxosCtx(ctx, from, 0)
+	if err != nil {
+		return err
+	}
+	if len(utxos.Utxos) < balance.UtxoNum {
+		return fmt.Errorf("payment %d: only %d of the %d UTXOs of %s are listed", payment.Id, len(utxos.Utxos), balance.UtxoNum, from)
+	}
+	unspent := make(map[string]bool, len(utxos.Utxos))
+	for _, utxo := range utxos.Utxos {
+		unspent[strings.ToLower(utxo.Ref.Key)] = true
+	}
+	for _, input := range tx.Inputs {
+		if !unspent[hex.EncodeToString(input.Key)] {
+			return nil
+		}
+	}
+	return fmt.Errorf("payment %d, tx %s, may still be confirmed, none of its inputs is spent", payment.Id, payment.TxId)
+}
+
+// createPayments debits the balances above the threshold into new pending payments
+func (e *Engine) createPayments() ([]int, error) {
+	addresses := make([]string, 0)
+	for addr, balance := range e.state.Balances {
+		if balance.Cmp(e.config.MinPayout) < 0 || balance.Cmp(e.config.TransactionFee) <= 0 {
+			continue
+		}
+		// an invalid address would fail the whole transaction, its balance is kept
+		if err := address.Validate(addr); err != nil {
+			e.log.Warnf("Not paying the invalid address %q: %v", addr, err)
+			continue
+		}
+		addresses = append(addresses, addr)
+	}
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+	sort.Strings(addresses)
+
+	ids := make([]int, 0)
+	for start := 0; start < len(addresses); start += e.config.MaxDestinations {
+		end := start + e.config.MaxDestinations
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+		e.state.NextPaymentId++
+		payment := Payment{
+			Id:        e.state.NextPaymentId,
+			Status:    PaymentPending,
+			Debited:   make(map[string]alephium.ALPH),
+			CreatedAt: time.Now(),
+		}
+		for _, address := range addresses[start:end] {
+			balance := e.state.Balances[address]
+			payment.Destinations = append(payment.Destinations, alephium.TransactionDestination{
+				Address: address,
+				Amount:  balance.Subtract(e.config.TransactionFee),
+			})
+			payment.Debited[address] = balance
+			delete(e.state.Balances, address)
+		}
+		e.state.Payments = append(e.state.Payments, payment)
+		ids = append(ids, payment.Id)
+	}
+	return ids, e.save()
+}
+
+// execute moves a payment forward until it is confirmed or failed
+func (e *Engine) execute(ctx context.Context, id int) (Payment, error) {
+	for {
+		payment := e.payment(id)
+		switch payment.Status {
+		case PaymentConfirmed, PaymentFailed:
+			return payment, nil
+
+		case PaymentPending:
+			// nothing was signed yet, building a new transaction is safe
+			unsignedTx, err := e.client.BuildTransactionCtx(ctx, e.config.PublicKey, payment.Destinations)
+			if errors.Is(err, alephium.ErrInvalidAddress) || errors.Is(err, alephium.ErrBadRequest) {
+				// it would fail every time, the payment can be cancelled
+				e.log.Errorf("Payment %d can't be built: %v", id, err)
+				buildErr := err
+				err = e.update(id, func(p *Payment) {
+					p.Status = PaymentFailed
+					p.Error = buildErr.Error()
+				})
+				if err != nil {
+					return payment, err
+				}
+				continue
+			} else if err != nil {
+				return payment, err
+			}
+			signature, err := e.client.SignCtx(ctx, e.config.WalletName, unsignedTx.TxId)
+			if err != nil {
+				return payment, err
+			}
+			err = e.update(id, func(p *Payment) {
+				p.Status = PaymentSigned
+				p.UnsignedTx = unsignedTx.UnsignedTx
+				p.TxId = unsignedTx.TxId
+				p.Signature = signature
+				p.FromGroup = unsignedTx.FromGroup
+				p.ToGroup = unsignedTx.ToGroup
+			})
+			if err != nil {
+				return payment, err
+			}
+
+		case PaymentSigned:
+			// the transaction may have been submitted before a crash, check it first
+			status, err := e.client.GetTxStatusCtx(ctx, payment.TxId, payment.FromGroup, payment.ToGroup)
+			if err != nil && !errors.Is(err, alephium.ErrNotFound) {
+				return payment, err
+			}
+			switch status.(type) {
+			case alephium.Confirmed:
+				err = e.update(id, func(p *Payment) { p.Status = PaymentConfirmed })
+			case alephium.MemPooled:
+				err = e.update(id, func(p *Payment) { p.Status = PaymentSubmitted })
+			default:
+				e.log.Infof("Submitting payment %d, tx %s", id, payment.TxId)
+				_, err = e.client.SubmitTransactionCtx(ctx, payment.UnsignedTx, payment.Signature)
+				var apiErr *alephium.APIError
+				if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+					// the transaction may have been submitted in the meantime: the next loop checks
+					// its status again, Cancel makes sure it can't be confirmed anymore
+					e.log.Errorf("Payment %d, tx %s, rejected: %v", id, payment.TxId, err)
+					if status, statusErr := e.client.GetTxStatusCtx(ctx, payment.TxId, payment.FromGroup, payment.ToGroup); statusErr == nil {
+						if _, notFound := status.(alephium.TxNotFound); !notFound {
+							continue
+						}
+					}
+					err = e.update(id, func(p *Payment) {
+						p.Status = PaymentFailed
+						p.Error = apiErr.Error()
+					})
+				} else if err == nil {
+					err = e.update(id, func(p *Payment) { p.Status = PaymentSubmitted })
+				}
+			}
+			if err != nil {
+				return payment, err
+			}
+
+		case PaymentSubmitted:
+			_, err := e.client.WaitForTransactionConfirmed(ctx, payment.TxId, payment.FromGroup, payment.ToGroup)
+			if err != nil {
+				return payment, err
+			}
+			e.log.Infof("Payment %d, tx %s, confirmed", id, payment.TxId)
+			if err := e.update(id, func(p *Payment) { p.Status = PaymentConfirmed }); err != nil {
+				return payment, err
+			}
+
+		default:
+			return payment, fmt.Errorf("payment %d has an unknown status %s", id, payment.Status)
+		}
+	}
+}
+
+func (e *Engine) payment(id int) Payment {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, payment := range e.state.Payments {
+		if payment.Id == id {
+			return payment
+		}
+	}
+	return Payment{Id: id}
+}
+
+// update applies the change to the payment and persists the state
+func (e *Engine) update(id int, change func(*Payment)) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := range e.state.Payments {
+		if e.state.Payments[i].Id == id {
+			change(&e.state.Payments[i])
+		}
+	}
+	return e.save()
+}
+
+// save persists the state, shares included
+func (e *Engine) save() error {
+	if err := e.store.Save(e.state); err != nil {
+		return err
+	}
+	e.dirty = false
+	e.savedAt = time.Now()
+	return nil
+}
+
+// credit adds the credits, minus the pool fee, to the balances
+func (e *Engine) credit(credits map[string]alephium.ALPH) {
+	net := make(map[string]alephium.ALPH, len(credits))
+	for address, amount := range credits {
+		net[address] = amount.Subtract(mulRatio(amount, e.config.PoolFee, 1))
+	}
+	e.addBalances(net)
+}
+
+func (e *Engine) addBalances(amounts map[string]alephium.ALPH) {
+	for address, amount := range amounts {
+		balance, ok := e.state.Balances[address]
+		if !ok {
+			balance = zero()
+		}
+		e.state.Balances[address] = balance.Add(amount)
+	}
+}
+
+// trimShares drops the oldest shares beyond the window of the scheme
+func (e *Engine) trimShares() {
+	window := e.config.Scheme.Window()
+	total := 0.0
+	i := len(e.state.Shares) - 1
+	for ; i >= 0 && total < window; i-- {
+		total += e.state.Shares[i].Difficulty
+	}
+	e.state.Shares = e.state.Shares[i+1:]
+}
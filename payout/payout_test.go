@@ -0,0 +1,307 @@
+package payout
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/codec"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeNode struct {
+	mu          sync.Mutex
+	builds      []alephium.BuildTransactionBodyRequest
+	submissions []string
+	failSubmits int
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/blockflow/blocks/block1":
+		_, _ = w.Write([]byte(`{"hash":"block1","timestamp":1620000000000,"chainFrom":0,"chainTo":0,"height":1,"deps":[],
+			"transactions":[{"id":"coinbase","inputs":[],"outputs":[{"amount":"3000000000000000000","address":"pool","lockTime":0}]}]}`))
+	case "/transactions/build":
+		var body alephium.BuildTransactionBodyRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		n.builds = append(n.builds, body)
+		_, _ = fmt.Fprintf(w, `{"unsignedTx":"unsigned-%d","txId":"tx-%d","fromGroup":0,"toGroup":0}`, len(n.builds), len(n.builds))
+	case "/wallets/pool/sign":
+		_, _ = w.Write([]byte(`{"signature":"signature"}`))
+	case "/transactions/submit":
+		var body alephium.SubmitTransactionBodyRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if n.failSubmits > 0 {
+			n.failSubmits--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"detail":"unavailable"}`))
+			return
+		}
+		n.submissions = append(n.submissions, body.UnsignedTx)
+		_, _ = w.Write([]byte(`{"txId":"tx","fromGroup":0,"toGroup":0}`))
+	case "/transactions/status":
+		txId := r.URL.Query().Get("txId")
+		for _, unsignedTx := range n.submissions {
+			if "tx-"+unsignedTx[len("unsigned-"):] == txId {
+				_, _ = w.Write([]byte(`{"type":"confirmed"}`))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"detail":"not found"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+const (
+	addressA = "1F7dCT2t2srmWUu5mtf67182TkvPQs9DcWf3YvgMnrVVi"
+	addressB = "1Ambgi1jNRcBcdDUSfyrY2uQXdHpJs3zfc7Nmt6NcpBbL"
+)
+
+func alph(s string) alephium.ALPH {
+	a, _ := alephium.ALPHFromALPHString(s)
+	return a
+}
+
+func TestSchemes(t *testing.T) {
+
+	shares := []Share{
+		{Address: "old", Difficulty: 10},
+		{Address: "a", Difficulty: 2},
+		{Address: "b", Difficulty: 1},
+		{Address: "a", Difficulty: 1},
+	}
+	credits := PPLNS{N: 5}.CreditBlock(Block{Reward: alph("10")}, shares)
+	assert.Equal(t, alph("6").String(), credits["a"].String())
+	assert.Equal(t, alph("2").String(), credits["b"].String())
+	// only 1 difficulty of the old share is in the window
+	assert.Equal(t, alph("2").String(), credits["old"].String())
+
+	credits = PPS{RewardPerDifficulty: alph("0.5")}.CreditShare(Share{Address: "a", Difficulty: 3})
+	assert.Equal(t, alph("1.5").String(), credits["a"].String())
+}
+
+func TestPayoutCrashRecovery(t *testing.T) {
+
+	node := &fakeNode{failSubmits: 1}
+	ts := httptest.NewServer(node)
+	defer ts.Close()
+	client, err := alephium.NewClient(ts.URL, alephium.WithPollInterval(10*time.Millisecond))
+	assert.Nil(t, err)
+
+	store := NewFileStore(filepath.Join(t.TempDir(), "payout.json"))
+	config := Config{
+		Scheme:         PPLNS{N: 10},
+		WalletName:     "pool",
+		PublicKey:      "publicKey",
+		PoolFee:        0.1,
+		MinPayout:      alph("1"),
+		TransactionFee: alph("0.01"),
+	}
+	engine, err := NewEngine(client, store, config)
+	assert.Nil(t, err)
+
+	assert.Nil(t, engine.AddShare(Share{Address: addressA, Difficulty: 2}))
+	assert.Nil(t, engine.AddShare(Share{Address: addressB, Difficulty: 1}))
+	assert.Nil(t, engine.ProcessBlock(context.Background(), "block1"))
+	// crediting the same block again is a no-op
+	assert.Nil(t, engine.CreditBlock(Block{Hash: "block1", Reward: alph("3")}))
+
+	balances := engine.Balances()
+	assert.Equal(t, alph("1.8").String(), balances[addressA].String())
+	assert.Equal(t, alph("0.9").String(), balances[addressB].String())
+
+	// the submission fails, as if the pool crashed after signing
+	_, err = engine.Payout(context.Background())
+	assert.NotNil(t, err)
+	payments := engine.Payments()
+	assert.Equal(t, 1, len(payments))
+	assert.Equal(t, PaymentSigned, payments[0].Status)
+	assert.Equal(t, 1, len(payments[0].Destinations))
+
+	// a new engine resumes from the file and submits the same transaction
+	engine, err = NewEngine(client, store, config)
+	assert.Nil(t, err)
+	confirmed, err := engine.Payout(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(confirmed))
+	assert.Equal(t, "tx-1", confirmed[0].TxId)
+
+	// nothing left to pay, except the balance of b below the threshold
+	confirmed, err = engine.Payout(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(confirmed))
+
+	assert.Equal(t, 1, len(node.builds))
+	assert.Equal(t, []string{"unsigned-1"}, node.submissions)
+	assert.Equal(t, addressA, node.builds[0].Destinations[0].Address)
+	assert.Equal(t, alph("1.79").String(), node.builds[0].Destinations[0].Amount.String())
+	assert.Equal(t, 1, len(engine.Balances()))
+}
+
+func TestPayoutBuildFailure(t *testing.T) {
+
+	var mu sync.Mutex
+	builds := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/transactions/build":
+			var body alephium.BuildTransactionBodyRequest
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			builds++
+			if body.Destinations[0].Address == addressB {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"detail":"not enough balance"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"unsignedTx":"unsigned","txId":"tx","fromGroup":0,"toGroup":0}`))
+		case "/wallets/pool/sign":
+			_, _ = w.Write([]byte(`{"signature":"signature"}`))
+		case "/transactions/submit":
+			_, _ = w.Write([]byte(`{"txId":"tx","fromGroup":0,"toGroup":0}`))
+		case "/transactions/status":
+			_, _ = w.Write([]byte(`{"type":"confirmed"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	client, err := alephium.NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	engine, err := NewEngine(client, NewFileStore(filepath.Join(t.TempDir(), "payout.json")), Config{
+		Scheme:          PPLNS{N: 10},
+		WalletName:      "pool",
+		PublicKey:       "publicKey",
+		MaxDestinations: 1,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, engine.AddShare(Share{Address: addressA, Difficulty: 2}))
+	assert.Nil(t, engine.AddShare(Share{Address: addressB, Difficulty: 1}))
+	assert.Nil(t, engine.AddShare(Share{Address: "invalid", Difficulty: 1}))
+	assert.Nil(t, engine.CreditBlock(Block{Hash: "block1", Reward: alph("4")}))
+
+	// the payment of b fails, a is paid anyway, the invalid address is not paid
+	confirmed, err := engine.Payout(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(confirmed))
+	assert.Equal(t, addressA, confirmed[0].Destinations[0].Address)
+	payments := engine.Payments()
+	assert.Equal(t, 2, len(payments))
+	assert.Equal(t, PaymentFailed, payments[0].Status)
+	assert.Equal(t, alph("1").String(), engine.Balances()["invalid"].String())
+
+	// the failed payment is not retried, but it can be cancelled
+	_, err = engine.Payout(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, builds)
+	assert.NotNil(t, engine.Cancel(payments[1].Id))
+	assert.Nil(t, engine.Cancel(payments[0].Id))
+	assert.Equal(t, alph("1").String(), engine.Balances()[addressB].String())
+}
+
+// countingStore counts the saves of the state
+type countingStore struct {
+	Store
+	saves int
+}
+
+func (s *countingStore) Save(state State) error {
+	s.saves++
+	return s.Store.Save(state)
+}
+
+func TestPayoutCancelRejected(t *testing.T) {
+
+	publicKey := "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	key := strings.Repeat("11", codec.HashLength)
+	to, err := address.Decode(addressA)
+	assert.Nil(t, err)
+	keyBytes, _ := hex.DecodeString(key)
+	publicKeyBytes, _ := hex.DecodeString(publicKey)
+	tx := codec.UnsignedTx{
+		GasAmount:    codec.MinimalGas,
+		GasPrice:     codec.DefaultGasPrice,
+		Inputs:       []codec.Input{codec.NewP2PKHInput(0, keyBytes, publicKeyBytes)},
+		FixedOutputs: []codec.Output{codec.NewOutput(alph("1").Amount, to)},
+	}
+
+	var mu sync.Mutex
+	spent := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/transactions/build":
+			_, _ = fmt.Fprintf(w, `{"unsignedTx":"%s","txId":"%s","fromGroup":0,"toGroup":0}`, tx.Hex(), tx.Id())
+		case r.URL.Path == "/wallets/pool/sign":
+			_, _ = w.Write([]byte(`{"signature":"signature"}`))
+		case r.URL.Path == "/transactions/submit":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"detail":"double spending"}`))
+		case r.URL.Path == "/transactions/status":
+			_, _ = w.Write([]byte(`{"type":"tx-not-found"}`))
+		case strings.HasSuffix(r.URL.Path, "/balance"):
+			_, _ = w.Write([]byte(`{"balance":"1000000000000000000","lockedBalance":"0","utxoNum":1}`))
+		case strings.HasSuffix(r.URL.Path, "/utxos"):
+			if spent {
+				key = strings.Repeat("22", codec.HashLength)
+			}
+			_, _ = fmt.Fprintf(w, `{"utxos":[{"ref":{"hint":0,"key":"%s"},"amount":"1000000000000000000","lockTime":0}]}`, key)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	client, err := alephium.NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	store := &countingStore{Store: NewFileStore(filepath.Join(t.TempDir(), "payout.json"))}
+	engine, err := NewEngine(client, store, Config{
+		Scheme:            PPLNS{N: 10},
+		WalletName:        "pool",
+		PublicKey:         publicKey,
+		ShareSaveInterval: time.Hour,
+	})
+	assert.Nil(t, err)
+
+	// the shares are saved with the block
+	assert.Nil(t, engine.AddShare(Share{Address: addressA, Difficulty: 1}))
+	assert.Nil(t, engine.AddShare(Share{Address: addressA, Difficulty: 1}))
+	assert.Equal(t, 0, store.saves)
+	assert.Nil(t, engine.CreditBlock(Block{Hash: "block1", Reward: alph("1")}))
+	assert.Equal(t, 1, store.saves)
+	assert.Nil(t, engine.Flush())
+	assert.Equal(t, 1, store.saves)
+
+	_, err = engine.Payout(context.Background())
+	assert.Nil(t, err)
+	payments := engine.Payments()
+	assert.Equal(t, PaymentFailed, payments[0].Status)
+	assert.Equal(t, 0, len(engine.Balances()))
+
+	// the input of the rejected transaction is not spent, it may still be confirmed
+	assert.NotNil(t, engine.Cancel(payments[0].Id))
+	mu.Lock()
+	spent = true
+	mu.Unlock()
+	assert.Nil(t, engine.Cancel(payments[0].Id))
+	assert.Equal(t, alph("1").String(), engine.Balances()[addressA].String())
+}
@@ -0,0 +1,101 @@
+package payout
+
+import (
+	"github.com/touilleio/alephium-go-client"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// Share is a share accepted by the pool, credited to the address of the worker
+type Share struct {
+	Address    string    `json:"address"`
+	Difficulty float64   `json:"difficulty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Block is a block found by the pool
+type Block struct {
+	Hash      string        `json:"hash"`
+	Reward    alephium.ALPH `json:"reward"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// Scheme computes what each address is owed, before the pool fee
+type Scheme interface {
+	// CreditShare is called for every share, with the recent shares including this one
+	CreditShare(share Share) map[string]alephium.ALPH
+	// CreditBlock is called for every block, with the recent shares, oldest first
+	CreditBlock(block Block, shares []Share) map[string]alephium.ALPH
+	// Window is the total difficulty of the recent shares to be kept
+	Window() float64
+}
+
+// PPLNS (pay per last N shares) splits the reward of every block between the shares of the last
+// N difficulty, proportionally to their difficulty.
+type PPLNS struct {
+	N float64
+}
+
+func (p PPLNS) CreditShare(share Share) map[string]alephium.ALPH {
+	return nil
+}
+
+func (p PPLNS) CreditBlock(block Block, shares []Share) map[string]alephium.ALPH {
+	weights := make(map[string]float64)
+	total := 0.0
+	for i := len(shares) - 1; i >= 0 && total < p.N; i-- {
+		weight := shares[i].Difficulty
+		if total+weight > p.N {
+			weight = p.N - total
+		}
+		weights[shares[i].Address] += weight
+		total += weight
+	}
+	credits := make(map[string]alephium.ALPH, len(weights))
+	for address, weight := range weights {
+		credits[address] = mulRatio(block.Reward, weight, total)
+	}
+	return credits
+}
+
+func (p PPLNS) Window() float64 {
+	return p.N
+}
+
+// PPS (pay per share) credits every share with a fixed reward per difficulty, whether blocks are found or not.
+type PPS struct {
+	RewardPerDifficulty alephium.ALPH
+}
+
+func (p PPS) CreditShare(share Share) map[string]alephium.ALPH {
+	return map[string]alephium.ALPH{share.Address: mulRatio(p.RewardPerDifficulty, share.Difficulty, 1)}
+}
+
+func (p PPS) CreditBlock(block Block, shares []Share) map[string]alephium.ALPH {
+	return nil
+}
+
+func (p PPS) Window() float64 {
+	return 0
+}
+
+// mulRatio multiplies an amount by num/den, rounding down. The floats are taken as their shortest
+// decimal representation, e.g. exactly 0.1 rather than its float64 approximation.
+func mulRatio(amount alephium.ALPH, num float64, den float64) alephium.ALPH {
+	if amount.Amount == nil || den == 0 {
+		return zero()
+	}
+	ratio := new(big.Rat).Quo(decimalRat(num), decimalRat(den))
+	product := new(big.Int).Mul(amount.Amount, ratio.Num())
+	return alephium.ALPH{Amount: product.Quo(product, ratio.Denom())}
+}
+
+func decimalRat(f float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	return r
+}
+
+func zero() alephium.ALPH {
+	return alephium.ALPH{Amount: new(big.Int)}
+}
@@ -0,0 +1,279 @@
+package pool
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/miner"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testHash = "bdaf9dc514ce7d34b6474b8ca10a3dfb93ba997cb9d5ff1ea724ebe2af48abe5"
+
+type fakeNode struct {
+	mu               sync.Mutex
+	minersAddresses  []string
+	solutions        []alephium.BlockSolution
+	candidateFetches int
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/infos/self-clique":
+		_, _ = w.Write([]byte(`{"synced":true,"groups":2}`))
+	case "/miners/addresses":
+		var body alephium.UpdateMinersAddressesBodyParams
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		n.minersAddresses = body.Addresses
+	case "/miners/block-candidate":
+		n.candidateFetches++
+		_, _ = fmt.Fprintf(w, `{"deps":["%s"],"depStateHash":"%s","target":"20010000","blockTs":%d,"txsHash":"%s","transactions":[]}`,
+			testHash, testHash, time.Now().UnixNano(), testHash)
+	case "/miners/new-block":
+		var solution alephium.BlockSolution
+		_ = json.NewDecoder(r.Body).Decode(&solution)
+		n.solutions = append(n.solutions, solution)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func startServer(t *testing.T, ctx context.Context, config Config) (*Server, *fakeNode) {
+	node := &fakeNode{}
+	ts := httptest.NewServer(node)
+	go func() {
+		<-ctx.Done()
+		ts.Close()
+	}()
+	client, err := alephium.NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	config.ListenAddress = "127.0.0.1:0"
+	server := NewServer(client, config)
+	assert.Nil(t, server.Listen())
+	go func() {
+		_ = server.Serve(ctx)
+	}()
+	return server, node
+}
+
+func TestPoolEndToEnd(t *testing.T) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	var shares []Share
+	var blocks []FoundBlock
+	server, node := startServer(t, ctx, Config{
+		Addresses:         []string{"1AujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n", "1BujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n"},
+		InitialDifficulty: 16,
+		OnShare: func(share Share) {
+			mu.Lock()
+			shares = append(shares, share)
+			mu.Unlock()
+		},
+		OnBlock: func(block FoundBlock) {
+			mu.Lock()
+			blocks = append(blocks, block)
+			mu.Unlock()
+		},
+	})
+
+	workers := []*Worker{
+		NewWorker(server.Addr().String(), "1AujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n.rig1", 1, nil),
+		NewWorker(server.Addr().String(), "1CujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n.rig2", 1, nil),
+	}
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	for _, worker := range workers {
+		go func(worker *Worker) {
+			_ = worker.Run(workersCtx)
+		}(worker)
+	}
+
+	for ctx.Err() == nil {
+		mu.Lock()
+		node.mu.Lock()
+		done := len(blocks) >= 2 && node.candidateFetches > 4
+		node.mu.Unlock()
+		mu.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// the stats are dropped with the sessions of the workers
+	stats := server.Workers()
+	stopWorkers()
+	assert.Nil(t, ctx.Err())
+
+	mu.Lock()
+	defer mu.Unlock()
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	assert.Equal(t, []string{"1AujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n", "1BujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n"}, node.minersAddresses)
+	assert.True(t, len(node.solutions) >= 2)
+	assert.True(t, len(shares) >= len(blocks))
+	assert.True(t, node.candidateFetches > 4, "candidates are refreshed after a block is found")
+
+	assert.Len(t, stats, 2)
+	for _, worker := range stats {
+		assert.True(t, worker.Accepted > 0)
+		assert.True(t, worker.ShareDifficulty >= 16)
+	}
+	for _, worker := range workers {
+		assert.True(t, worker.Accepted() > 0)
+	}
+	for _, block := range blocks {
+		assert.Contains(t, []string{"1AujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n", "1CujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n"}, block.Address)
+	}
+}
+
+func TestPoolProtocolErrors(t *testing.T) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server, _ := startServer(t, ctx, Config{Groups: 2, RetargetShares: 1, TargetShareInterval: time.Hour})
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	reader := bufio.NewScanner(conn)
+	reader.Buffer(make([]byte, 4096), 1024*1024)
+	send := func(id int64, method string, params ...string) {
+		raw, _ := json.Marshal(params)
+		b, _ := json.Marshal(Request{Id: &id, Method: method, Params: raw})
+		_, err := conn.Write(append(b, '\n'))
+		assert.Nil(t, err)
+	}
+	receive := func() Message {
+		assert.True(t, reader.Scan())
+		var message Message
+		assert.Nil(t, json.Unmarshal(reader.Bytes(), &message))
+		return message
+	}
+
+	send(1, MethodSubmit, "rig", "1", "42")
+	assert.Equal(t, float64(ErrCodeUnauthorized), receive().Error[0])
+
+	send(2, "mining.unknown")
+	assert.Equal(t, float64(ErrCodeUnknownMethod), receive().Error[0])
+
+	send(3, MethodSubscribe)
+	assert.Nil(t, receive().Error)
+	send(4, MethodAuthorize, "invalid.rig", "")
+	assert.Equal(t, float64(ErrCodeInvalidRequest), receive().Error[0])
+	send(4, MethodAuthorize, "1AujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n.rig", "")
+	assert.Nil(t, receive().Error)
+	message := receive()
+	assert.Equal(t, MethodSetDifficulty, message.Method)
+	message = receive()
+	assert.Equal(t, MethodNotify, message.Method)
+	assert.Len(t, message.Params, 4)
+	var job Job
+	assert.Nil(t, json.Unmarshal(message.Params[0], &job))
+
+	send(5, MethodSubmit, "rig", "unknown-job", "42")
+	assert.Equal(t, float64(ErrCodeStaleJob), receive().Error[0])
+	tooLarge := new(big.Int).Add(miner.MaxNonce, big.NewInt(1))
+	send(5, MethodSubmit, "rig", job.JobId, tooLarge.String())
+	assert.Equal(t, float64(ErrCodeInvalidRequest), receive().Error[0])
+
+	// find a valid share for the job, submit it twice
+	nonce := findShare(t, job, TargetForDifficulty(DefaultInitialDifficulty))
+	send(6, MethodSubmit, "rig", job.JobId, nonce)
+	message = receive()
+	assert.Nil(t, message.Error)
+	// the difficulty is retargeted after every share, shares being far more frequent than expected
+	message = receive()
+	assert.Equal(t, MethodSetDifficulty, message.Method)
+	var difficulty float64
+	assert.Nil(t, json.Unmarshal(message.Params[0], &difficulty))
+	assert.Equal(t, float64(4*DefaultInitialDifficulty), difficulty)
+
+	send(7, MethodSubmit, "rig", job.JobId, nonce)
+	assert.Equal(t, float64(ErrCodeDuplicateShare), receive().Error[0])
+
+	stats := server.Workers()
+	assert.Len(t, stats, 1)
+	assert.Equal(t, uint64(1), stats[0].Accepted)
+	assert.Equal(t, uint64(1), stats[0].Rejected)
+	assert.Equal(t, uint64(1), stats[0].Stale)
+	assert.Equal(t, float64(4*DefaultInitialDifficulty), stats[0].Difficulty)
+
+	// the stats are kept while the worker has a session
+	other, err := net.Dial("tcp", server.Addr().String())
+	assert.Nil(t, err)
+	defer other.Close()
+	raw, _ := json.Marshal([]string{"1AujpupFP4KWeZvqA7itsHY9cLJmx4qTzojVZrg8W9y9n.rig", ""})
+	b, _ := json.Marshal(Request{Method: MethodAuthorize, Params: raw})
+	_, err = other.Write(append(b, '\n'))
+	assert.Nil(t, err)
+	assert.True(t, bufio.NewScanner(other).Scan())
+	_ = conn.Close()
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, server.Workers(), 1)
+	_ = other.Close()
+	for len(server.Workers()) > 0 && ctx.Err() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Len(t, server.Workers(), 0)
+}
+
+func TestPoolServeError(t *testing.T) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	node := httptest.NewServer(&fakeNode{})
+	defer node.Close()
+	client, err := alephium.NewClient(node.URL)
+	assert.Nil(t, err)
+
+	server := NewServer(client, Config{Groups: 2, ListenAddress: "127.0.0.1:0"})
+	assert.Nil(t, server.Listen())
+	served := make(chan error)
+	go func() {
+		served <- server.Serve(ctx)
+	}()
+	conn, err := net.Dial("tcp", server.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	// accepting fails, the context is not done
+	_ = server.listener.Close()
+	select {
+	case err := <-served:
+		assert.NotNil(t, err)
+		assert.Nil(t, ctx.Err())
+	case <-ctx.Done():
+		t.Fatal("Serve didn't return")
+	}
+}
+
+func findShare(t *testing.T, job Job, target *big.Int) string {
+	prefix, err := hex.DecodeString(job.HeaderPrefix)
+	assert.Nil(t, err)
+	nonce := new(big.Int)
+	for i := int64(0); ; i++ {
+		nonce.SetInt64(i)
+		hash := miner.HashHeader(prefix, nonce)
+		fromGroup, toGroup := miner.ChainIndex(hash, job.Groups)
+		if miner.CheckTarget(hash, target) && fromGroup == job.FromGroup && toGroup == job.ToGroup {
+			return nonce.String()
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package pool is a mining pool server exposing a Stratum-like protocol to remote workers,
+// in front of a full node providing the block candidates.
+//
+// The protocol is made of JSON messages, one per line, over TCP:
+//
+//   - mining.subscribe, worker -> pool: subscribes to the jobs
+//   - mining.authorize, worker -> pool: params are "<address>.<worker name>" and an unused password
+//   - mining.set_difficulty, pool -> worker: params are the share difficulty
+//   - mining.notify, pool -> worker: params are the jobs, one per chain, replacing the previous ones
+//   - mining.submit, worker -> pool: params are the worker name, the job id and the nonce
+//
+// A job carries the serialized header without its nonce. The proof of work hash is the double blake3
+// hash of the header followed by the compact encoded nonce, see miner.HashHeader.
+package pool
+
+import (
+	"encoding/json"
+	"math/big"
+)
+
+const (
+	MethodSubscribe     = "mining.subscribe"
+	MethodAuthorize     = "mining.authorize"
+	MethodSetDifficulty = "mining.set_difficulty"
+	MethodNotify        = "mining.notify"
+	MethodSubmit        = "mining.submit"
+)
+
+// Error codes returned to the workers
+const (
+	ErrCodeUnknown         = 20
+	ErrCodeStaleJob        = 21
+	ErrCodeDuplicateShare  = 22
+	ErrCodeLowDifficulty   = 23
+	ErrCodeUnauthorized    = 24
+	ErrCodeNotSubscribed   = 25
+	ErrCodeInvalidRequest  = 26
+	ErrCodeUnknownMethod   = 27
+	ErrCodeInvalidSolution = 28
+)
+
+// Request is a message sent by a worker
+type Request struct {
+	Id     *int64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// Response is the answer of the pool to a Request
+type Response struct {
+	Id     *int64        `json:"id"`
+	Result interface{}   `json:"result"`
+	Error  []interface{} `json:"error"`
+}
+
+// Notification is a message sent by the pool without being requested
+type Notification struct {
+	Id     *int64        `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// Message is any message received by a worker, either a Response or a Notification
+type Message struct {
+	Id     *int64            `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	Result json.RawMessage   `json:"result"`
+	Error  []interface{}     `json:"error"`
+}
+
+// Job is a block template sent to the workers
+type Job struct {
+	JobId        string `json:"jobId"`
+	FromGroup    int    `json:"fromGroup"`
+	ToGroup      int    `json:"toGroup"`
+	Groups       int    `json:"groups"`
+	HeaderPrefix string `json:"headerBlob"`
+}
+
+func newError(code int, message string) []interface{} {
+	return []interface{}{code, message, nil}
+}
+
+// maxTarget is the target of a share of difficulty 1
+var maxTarget = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// TargetForDifficulty returns the target a hash must be below to be a share of the given difficulty
+func TargetForDifficulty(difficulty float64) *big.Int {
+	if difficulty <= 1 {
+		return new(big.Int).Set(maxTarget)
+	}
+	target, _ := new(big.Float).Quo(new(big.Float).SetInt(maxTarget), big.NewFloat(difficulty)).Int(nil)
+	return target
+}
+
+// DifficultyForTarget is the inverse of TargetForDifficulty
+func DifficultyForTarget(target *big.Int) float64 {
+	if target.Sign() <= 0 {
+		return 0
+	}
+	difficulty, _ := new(big.Float).Quo(new(big.Float).SetInt(maxTarget), new(big.Float).SetInt(target)).Float64()
+	return difficulty
+}
@@ -0,0 +1,559 @@
+package pool
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/address"
+	"github.com/touilleio/alephium-go-client/miner"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRefreshInterval     = 10 * time.Second
+	DefaultInitialDifficulty   = 1024
+	DefaultMinDifficulty       = 1
+	DefaultTargetShareInterval = 10 * time.Second
+	DefaultRetargetShares      = 10
+
+	writeTimeout = 10 * time.Second
+)
+
+// Config configures the Server. All the fields but ListenAddress are optional.
+type Config struct {
+	// ListenAddress is the TCP address the workers connect to, e.g. ":20032"
+	ListenAddress string
+	// Addresses of the pool, one per group, receiving the block rewards. The miner addresses
+	// of the node are left untouched if empty.
+	Addresses []string
+	// Groups is the number of groups of the clique, fetched from the node if 0
+	Groups int
+	// RefreshInterval is the interval after which the candidates are fetched again, DefaultRefreshInterval if 0
+	RefreshInterval time.Duration
+	// InitialDifficulty is the share difficulty of a new worker, DefaultInitialDifficulty if 0
+	InitialDifficulty float64
+	// MinDifficulty is the lowest share difficulty of a worker, DefaultMinDifficulty if 0
+	MinDifficulty float64
+	// TargetShareInterval is the interval between two shares of a worker the difficulty
+	// is adjusted for, DefaultTargetShareInterval if 0
+	TargetShareInterval time.Duration
+	// RetargetShares is the number of shares after which the difficulty of a worker is adjusted,
+	// DefaultRetargetShares if 0
+	RetargetShares int
+	// Log is the logger of the server, nothing is logged if nil
+	Log alephium.Logger
+	// OnShare, if not nil, is called for every accepted share
+	OnShare func(Share)
+	// OnBlock, if not nil, is called for every block accepted by the node
+	OnBlock func(FoundBlock)
+}
+
+// Share is a share accepted by the pool
+type Share struct {
+	Worker     string
+	Address    string
+	Difficulty float64
+	JobId      string
+	Timestamp  time.Time
+}
+
+// FoundBlock is a block found by a worker and accepted by the node
+type FoundBlock struct {
+	Hash      string
+	FromGroup int
+	ToGroup   int
+	Worker    string
+	Address   string
+	Timestamp time.Time
+}
+
+// WorkerStats are the statistics of a worker, kept across its sessions and dropped with its last one
+type WorkerStats struct {
+	Worker          string
+	Address         string
+	Difficulty      float64
+	Accepted        uint64
+	Rejected        uint64
+	Stale           uint64
+	Blocks          uint64
+	ShareDifficulty float64
+	LastShare       time.Time
+}
+
+// Server is a mining pool server
+type Server struct {
+	client *alephium.Client
+	config Config
+	log    alephium.Logger
+
+	listener net.Listener
+	groups   int
+	refresh  chan struct{}
+
+	mu        sync.RWMutex
+	jobs      map[string]*miner.Job
+	notified  []interface{}
+	nonces    map[string]struct{}
+	jobNumber uint64
+	sessions  map[*session]struct{}
+	stats     map[string]*WorkerStats
+	// users are the numbers of sessions authorized by user
+	users map[string]int
+}
+
+// NewServer creates a pool server fetching candidates and submitting blocks through the client
+func NewServer(client *alephium.Client, config Config) *Server {
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = DefaultRefreshInterval
+	}
+	if config.InitialDifficulty <= 0 {
+		config.InitialDifficulty = DefaultInitialDifficulty
+	}
+	if config.MinDifficulty <= 0 {
+		config.MinDifficulty = DefaultMinDifficulty
+	}
+	if config.TargetShareInterval <= 0 {
+		config.TargetShareInterval = DefaultTargetShareInterval
+	}
+	if config.RetargetShares <= 0 {
+		config.RetargetShares = DefaultRetargetShares
+	}
+	log := config.Log
+	if log == nil {
+		log = alephium.NopLogger{}
+	}
+	return &Server{
+		client:   client,
+		config:   config,
+		log:      log,
+		refresh:  make(chan struct{}, 1),
+		jobs:     make(map[string]*miner.Job),
+		nonces:   make(map[string]struct{}),
+		sessions: make(map[*session]struct{}),
+		stats:    make(map[string]*WorkerStats),
+		users:    make(map[string]int),
+	}
+}
+
+// Listen binds the listen address, see Addr
+func (s *Server) Listen() error {
+	listener, err := net.Listen("tcp", s.config.ListenAddress)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	return nil
+}
+
+// Addr returns the address the server listens on, nil before Listen
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Run listens and serves the workers until the context is done
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	return s.Serve(ctx)
+}
+
+// Serve serves the workers until the context is done. Listen must be called before.
+func (s *Server) Serve(ctx context.Context) error {
+	if s.listener == nil {
+		return fmt.Errorf("Listen must be called before Serve")
+	}
+	defer s.listener.Close()
+
+	if len(s.config.Addresses) > 0 {
+		if err := s.client.UpdateMinersAddressesCtx(ctx, s.config.Addresses); err != nil {
+			return fmt.Errorf("update miners addresses: %w", err)
+		}
+	}
+	s.groups = s.config.Groups
+	if s.groups <= 0 {
+		selfClique, err := s.client.GetSelfCliqueInfosCtx(ctx)
+		if err != nil {
+			return err
+		}
+		s.groups = selfClique.Groups
+	}
+	s.refreshJobs(ctx)
+
+	// serveCtx stops the goroutines when Serve returns, the context being done or not
+	serveCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.refreshLoop(serveCtx)
+	}()
+	go func() {
+		<-serveCtx.Done()
+		_ = s.listener.Close()
+		s.mu.RLock()
+		for sess := range s.sessions {
+			_ = sess.conn.Close()
+		}
+		s.mu.RUnlock()
+	}()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			cancel()
+			wg.Wait()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		sess := s.newSession(conn)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handle(serveCtx, sess)
+		}()
+	}
+}
+
+// Workers returns the statistics of every worker which ever connected
+func (s *Server) Workers() []WorkerStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	workers := make([]WorkerStats, 0, len(s.stats))
+	for _, stats := range s.stats {
+		workers = append(workers, *stats)
+	}
+	return workers
+}
+
+func (s *Server) refreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.config.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.refresh:
+		}
+		s.refreshJobs(ctx)
+	}
+}
+
+// refreshJobs fetches new candidates for every chain and notifies the workers
+func (s *Server) refreshJobs(ctx context.Context) {
+	jobs := make(map[string]*miner.Job)
+	notified := make([]interface{}, 0, s.groups*s.groups)
+	for fromGroup := 0; fromGroup < s.groups; fromGroup++ {
+		for toGroup := 0; toGroup < s.groups; toGroup++ {
+			candidate, err := s.client.GetBlockCandidateCtx(ctx, fromGroup, toGroup)
+			if err != nil {
+				s.log.Debugf("Failed to get block candidate for chain %d -> %d: %v", fromGroup, toGroup, err)
+				continue
+			}
+			job, err := miner.NewJob(fromGroup, toGroup, s.groups, candidate)
+			if err != nil {
+				s.log.Warnf("Invalid block candidate for chain %d -> %d: %v", fromGroup, toGroup, err)
+				continue
+			}
+			s.mu.Lock()
+			s.jobNumber++
+			jobId := strconv.FormatUint(s.jobNumber, 16)
+			s.mu.Unlock()
+			jobs[jobId] = job
+			notified = append(notified, Job{
+				JobId:        jobId,
+				FromGroup:    fromGroup,
+				ToGroup:      toGroup,
+				Groups:       s.groups,
+				HeaderPrefix: hex.EncodeToString(job.HeaderPrefix()),
+			})
+		}
+	}
+
+	s.mu.Lock()
+	s.jobs = jobs
+	s.notified = notified
+	s.nonces = make(map[string]struct{})
+	sessions := make([]*session, 0, len(s.sessions))
+	for sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.mu.Unlock()
+
+	for _, sess := range sessions {
+		if sess.ready() {
+			sess.resetPreviousDifficulty()
+			sess.notify(MethodNotify, notified)
+		}
+	}
+}
+
+func (s *Server) handle(ctx context.Context, sess *session) {
+	s.mu.Lock()
+	s.sessions[sess] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		sess.mu.Lock()
+		user := sess.worker
+		sess.mu.Unlock()
+		s.mu.Lock()
+		delete(s.sessions, sess)
+		s.release(user)
+		s.mu.Unlock()
+		_ = sess.conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(sess.conn)
+	scanner.Buffer(make([]byte, 4096), 1024*1024)
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			s.log.Debugf("Invalid request from %s: %v", sess.conn.RemoteAddr(), err)
+			sess.respond(nil, nil, newError(ErrCodeInvalidRequest, "invalid request"))
+			continue
+		}
+		switch req.Method {
+		case MethodSubscribe:
+			sess.mu.Lock()
+			sess.subscribed = true
+			sess.mu.Unlock()
+			sess.respond(req.Id, []string{sess.id}, nil)
+			s.sendWork(sess)
+		case MethodAuthorize:
+			s.authorize(sess, req)
+		case MethodSubmit:
+			s.submit(ctx, sess, req)
+		default:
+			sess.respond(req.Id, nil, newError(ErrCodeUnknownMethod, "unknown method "+req.Method))
+		}
+	}
+}
+
+func (s *Server) authorize(sess *session, req Request) {
+	var params []string
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 || params[0] == "" {
+		sess.respond(req.Id, false, newError(ErrCodeInvalidRequest, "expected <address>.<worker name>"))
+		return
+	}
+	user := params[0]
+	addr := strings.SplitN(user, ".", 2)[0]
+	// the address is the destination of the payouts of the worker
+	if err := address.Validate(addr); err != nil {
+		sess.respond(req.Id, false, newError(ErrCodeInvalidRequest, fmt.Sprintf("invalid address %q: %v", addr, err)))
+		return
+	}
+
+	sess.mu.Lock()
+	previous := sess.worker
+	sess.mu.Unlock()
+	s.mu.Lock()
+	stats, ok := s.stats[user]
+	if !ok {
+		stats = &WorkerStats{Worker: user, Address: addr, Difficulty: s.config.InitialDifficulty}
+		s.stats[user] = stats
+	}
+	if previous != user {
+		s.users[user]++
+		s.release(previous)
+	}
+	difficulty := stats.Difficulty
+	s.mu.Unlock()
+
+	sess.mu.Lock()
+	sess.worker = user
+	sess.address = addr
+	sess.difficulty = difficulty
+	sess.previousDifficulty = difficulty
+	sess.lastRetarget = time.Now()
+	sess.mu.Unlock()
+
+	s.log.Infof("Worker %s authorized from %s", user, sess.conn.RemoteAddr())
+	sess.respond(req.Id, true, nil)
+	s.sendWork(sess)
+}
+
+// release drops the stats of the user once it has no session left, s.mu being held
+func (s *Server) release(user string) {
+	if user == "" {
+		return
+	}
+	s.users[user]--
+	if s.users[user] <= 0 {
+		delete(s.users, user)
+		delete(s.stats, user)
+	}
+}
+
+// sendWork sends the difficulty and the current jobs once the worker is subscribed and authorized
+func (s *Server) sendWork(sess *session) {
+	if !sess.ready() {
+		return
+	}
+	s.mu.RLock()
+	notified := s.notified
+	s.mu.RUnlock()
+	sess.mu.Lock()
+	difficulty := sess.difficulty
+	sess.mu.Unlock()
+	sess.notify(MethodSetDifficulty, []interface{}{difficulty})
+	sess.notify(MethodNotify, notified)
+}
+
+func (s *Server) submit(ctx context.Context, sess *session, req Request) {
+	if !sess.ready() {
+		sess.respond(req.Id, false, newError(ErrCodeUnauthorized, "unauthorized worker"))
+		return
+	}
+	var params []string
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) != 3 {
+		sess.respond(req.Id, false, newError(ErrCodeInvalidRequest, "expected worker, job id and nonce"))
+		return
+	}
+	jobId := params[1]
+	nonce, ok := new(big.Int).SetString(params[2], 10)
+	if !ok || nonce.Sign() < 0 || nonce.Cmp(miner.MaxNonce) > 0 {
+		sess.respond(req.Id, false, newError(ErrCodeInvalidRequest, "invalid nonce"))
+		return
+	}
+
+	s.mu.Lock()
+	job, ok := s.jobs[jobId]
+	stats := s.stats[sess.worker]
+	if !ok {
+		stats.Stale++
+		s.mu.Unlock()
+		sess.respond(req.Id, false, newError(ErrCodeStaleJob, "stale job"))
+		return
+	}
+	key := jobId + ":" + nonce.String()
+	if _, duplicate := s.nonces[key]; duplicate {
+		stats.Rejected++
+		s.mu.Unlock()
+		sess.respond(req.Id, false, newError(ErrCodeDuplicateShare, "duplicate share"))
+		return
+	}
+	s.nonces[key] = struct{}{}
+	s.mu.Unlock()
+
+	hash := job.Hash(nonce)
+	difficulty := sess.shareDifficulty()
+	shareTarget := TargetForDifficulty(difficulty)
+	if shareTarget.Cmp(job.Target) < 0 {
+		shareTarget = job.Target
+		difficulty = DifficultyForTarget(job.Target)
+	}
+	if fromGroup, toGroup := miner.ChainIndex(hash, s.groups); fromGroup != job.FromGroup || toGroup != job.ToGroup {
+		s.reject(stats)
+		sess.respond(req.Id, false, newError(ErrCodeInvalidSolution, "hash of another chain"))
+		return
+	}
+	if !miner.CheckTarget(hash, shareTarget) {
+		s.reject(stats)
+		sess.respond(req.Id, false, newError(ErrCodeLowDifficulty, "low difficulty share"))
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	stats.Accepted++
+	stats.ShareDifficulty += difficulty
+	stats.LastShare = now
+	s.mu.Unlock()
+	sess.respond(req.Id, true, nil)
+
+	share := Share{Worker: sess.worker, Address: sess.address, Difficulty: difficulty, JobId: jobId, Timestamp: now}
+	if s.config.OnShare != nil {
+		s.config.OnShare(share)
+	}
+
+	if miner.CheckTarget(hash, job.Target) {
+		s.submitBlock(ctx, sess, job, nonce, hash, stats)
+	}
+	s.retarget(sess, stats)
+}
+
+func (s *Server) reject(stats *WorkerStats) {
+	s.mu.Lock()
+	stats.Rejected++
+	s.mu.Unlock()
+}
+
+func (s *Server) submitBlock(ctx context.Context, sess *session, job *miner.Job, nonce *big.Int, hash [32]byte, stats *WorkerStats) {
+	// the hashes computed by the workers for the job are not known
+	err := s.client.SubmitBlockSolutionCtx(ctx, job.Solution(nonce, 0))
+	if err != nil {
+		s.log.Warnf("Block found by %s on chain %d -> %d rejected by the node: %v", sess.worker, job.FromGroup, job.ToGroup, err)
+		return
+	}
+	block := FoundBlock{
+		Hash:      hex.EncodeToString(hash[:]),
+		FromGroup: job.FromGroup,
+		ToGroup:   job.ToGroup,
+		Worker:    sess.worker,
+		Address:   sess.address,
+		Timestamp: time.Now(),
+	}
+	s.log.Infof("Block %s found by %s on chain %d -> %d", block.Hash, block.Worker, block.FromGroup, block.ToGroup)
+	s.mu.Lock()
+	stats.Blocks++
+	s.mu.Unlock()
+	if s.config.OnBlock != nil {
+		s.config.OnBlock(block)
+	}
+	select {
+	case s.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// retarget adjusts the difficulty of the worker every RetargetShares shares, aiming at one share every TargetShareInterval
+func (s *Server) retarget(sess *session, stats *WorkerStats) {
+	sess.mu.Lock()
+	sess.sharesSinceRetarget++
+	if sess.sharesSinceRetarget < s.config.RetargetShares {
+		sess.mu.Unlock()
+		return
+	}
+	elapsed := time.Since(sess.lastRetarget)
+	ratio := float64(s.config.TargetShareInterval) * float64(sess.sharesSinceRetarget) / float64(elapsed)
+	if ratio > 4 {
+		ratio = 4
+	} else if ratio < 0.25 {
+		ratio = 0.25
+	}
+	difficulty := sess.difficulty * ratio
+	if difficulty < s.config.MinDifficulty {
+		difficulty = s.config.MinDifficulty
+	}
+	sess.sharesSinceRetarget = 0
+	sess.lastRetarget = time.Now()
+	changed := difficulty < sess.difficulty*0.9 || difficulty > sess.difficulty*1.1
+	if changed {
+		sess.previousDifficulty = sess.difficulty
+		sess.difficulty = difficulty
+	}
+	sess.mu.Unlock()
+
+	if changed {
+		s.mu.Lock()
+		stats.Difficulty = difficulty
+		s.mu.Unlock()
+		s.log.Debugf("Difficulty of %s set to %f", sess.worker, difficulty)
+		sess.notify(MethodSetDifficulty, []interface{}{difficulty})
+	}
+}
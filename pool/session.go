@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var sessionCounter uint64
+
+// session is the connection of a worker to the server
+type session struct {
+	id   string
+	conn net.Conn
+
+	writeMu sync.Mutex
+
+	mu                  sync.Mutex
+	subscribed          bool
+	worker              string
+	address             string
+	difficulty          float64
+	previousDifficulty  float64
+	sharesSinceRetarget int
+	lastRetarget        time.Time
+}
+
+func (s *Server) newSession(conn net.Conn) *session {
+	return &session{
+		id:   fmt.Sprintf("%08x", atomic.AddUint64(&sessionCounter, 1)),
+		conn: conn,
+	}
+}
+
+// ready returns true once the worker is subscribed and authorized
+func (sess *session) ready() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.subscribed && sess.worker != ""
+}
+
+// shareDifficulty is the lowest of the current and the previous difficulty,
+// shares sent before the worker got the new difficulty being accepted
+func (sess *session) shareDifficulty() float64 {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.previousDifficulty > 0 && sess.previousDifficulty < sess.difficulty {
+		return sess.previousDifficulty
+	}
+	return sess.difficulty
+}
+
+// resetPreviousDifficulty is called when new jobs are sent, along with the current difficulty
+func (sess *session) resetPreviousDifficulty() {
+	sess.mu.Lock()
+	sess.previousDifficulty = sess.difficulty
+	sess.mu.Unlock()
+}
+
+func (sess *session) respond(id *int64, result interface{}, err []interface{}) {
+	sess.send(Response{Id: id, Result: result, Error: err})
+}
+
+func (sess *session) notify(method string, params []interface{}) {
+	sess.send(Notification{Method: method, Params: params})
+}
+
+func (sess *session) send(message interface{}) {
+	b, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	sess.writeMu.Lock()
+	defer sess.writeMu.Unlock()
+	_ = sess.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, _ = sess.conn.Write(append(b, '\n'))
+}
@@ -0,0 +1,194 @@
+package pool
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/touilleio/alephium-go-client"
+	"github.com/touilleio/alephium-go-client/miner"
+	"math/big"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Worker is a CPU worker mining for a pool server. Like the miner package, it is meant for tests.
+type Worker struct {
+	poolAddress string
+	user        string
+	goroutines  int
+	log         alephium.Logger
+
+	conn      net.Conn
+	writeMu   sync.Mutex
+	requestId int64
+
+	mu     sync.Mutex
+	target *big.Int
+	cancel context.CancelFunc
+
+	submitted uint64
+	accepted  uint64
+	rejected  uint64
+}
+
+// NewWorker creates a worker connecting to the pool at poolAddress as user, i.e. "<address>.<worker name>",
+// searching nonces on the given number of goroutines
+func NewWorker(poolAddress string, user string, goroutines int, log alephium.Logger) *Worker {
+	if goroutines <= 0 {
+		goroutines = 1
+	}
+	if log == nil {
+		log = alephium.NopLogger{}
+	}
+	return &Worker{
+		poolAddress: poolAddress,
+		user:        user,
+		goroutines:  goroutines,
+		log:         log,
+		target:      TargetForDifficulty(DefaultInitialDifficulty),
+	}
+}
+
+// Run connects to the pool and mines until the context is done or the connection is closed
+func (w *Worker) Run(ctx context.Context) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", w.poolAddress)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	defer w.stopMining()
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	w.request(MethodSubscribe, []string{})
+	w.request(MethodAuthorize, []string{w.user, ""})
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), 1024*1024)
+	for scanner.Scan() {
+		var message Message
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			w.log.Warnf("Invalid message from the pool: %v", err)
+			continue
+		}
+		switch message.Method {
+		case MethodSetDifficulty:
+			var difficulty float64
+			if len(message.Params) == 1 && json.Unmarshal(message.Params[0], &difficulty) == nil {
+				w.mu.Lock()
+				w.target = TargetForDifficulty(difficulty)
+				w.mu.Unlock()
+			}
+		case MethodNotify:
+			jobs := make([]Job, 0, len(message.Params))
+			for _, param := range message.Params {
+				var job Job
+				if err := json.Unmarshal(param, &job); err == nil {
+					jobs = append(jobs, job)
+				}
+			}
+			w.startMining(ctx, jobs)
+		case "":
+			// response to a submit, subscribe and authorize being the first 2 requests
+			if message.Id != nil && *message.Id > 2 {
+				var result bool
+				_ = json.Unmarshal(message.Result, &result)
+				if result {
+					atomic.AddUint64(&w.accepted, 1)
+				} else {
+					atomic.AddUint64(&w.rejected, 1)
+					w.log.Debugf("Share rejected: %v", message.Error)
+				}
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
+
+// Submitted returns the number of shares submitted to the pool
+func (w *Worker) Submitted() uint64 {
+	return atomic.LoadUint64(&w.submitted)
+}
+
+// Accepted returns the number of shares accepted by the pool
+func (w *Worker) Accepted() uint64 {
+	return atomic.LoadUint64(&w.accepted)
+}
+
+// Rejected returns the number of shares rejected by the pool
+func (w *Worker) Rejected() uint64 {
+	return atomic.LoadUint64(&w.rejected)
+}
+
+func (w *Worker) startMining(ctx context.Context, jobs []Job) {
+	w.stopMining()
+	if len(jobs) == 0 {
+		return
+	}
+	prefixes := make([][]byte, len(jobs))
+	for i, job := range jobs {
+		prefix, err := hex.DecodeString(job.HeaderPrefix)
+		if err != nil {
+			w.log.Warnf("Invalid job %s: %v", job.JobId, err)
+			return
+		}
+		prefixes[i] = prefix
+	}
+
+	mineCtx, cancel := context.WithCancel(ctx)
+	w.mu.Lock()
+	w.cancel = cancel
+	w.mu.Unlock()
+
+	for g := 0; g < w.goroutines; g++ {
+		go func(g int) {
+			nonce := new(big.Int)
+			start := rand.Uint64()
+			for i := uint64(0); mineCtx.Err() == nil; i++ {
+				idx := (g + int(i)) % len(jobs)
+				job := jobs[idx]
+				nonce.SetUint64(start + i)
+				hash := miner.HashHeader(prefixes[idx], nonce)
+				w.mu.Lock()
+				target := w.target
+				w.mu.Unlock()
+				if !miner.CheckTarget(hash, target) {
+					continue
+				}
+				if fromGroup, toGroup := miner.ChainIndex(hash, job.Groups); fromGroup != job.FromGroup || toGroup != job.ToGroup {
+					continue
+				}
+				atomic.AddUint64(&w.submitted, 1)
+				w.request(MethodSubmit, []string{w.user, job.JobId, nonce.String()})
+			}
+		}(g)
+	}
+}
+
+func (w *Worker) stopMining() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+}
+
+func (w *Worker) request(method string, params interface{}) {
+	id := atomic.AddInt64(&w.requestId, 1)
+	raw, _ := json.Marshal(params)
+	b, _ := json.Marshal(Request{Id: &id, Method: method, Params: raw})
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	_ = w.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, _ = w.conn.Write(append(b, '\n'))
+}
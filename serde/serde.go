@@ -0,0 +1,146 @@
+// Package serde implements the binary encoding used by Alephium to serialize
+// blocks and transactions: compact integers and length-prefixed byte strings.
+package serde
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	singleByteMode = 0x00
+	twoByteMode    = 0x40
+	fourByteMode   = 0x80
+	multiByteMode  = 0xc0
+	modeMask       = 0xc0
+	valueMask      = 0x3f
+)
+
+// ErrUnexpectedEnd is returned when the input is too short to be decoded
+var ErrUnexpectedEnd = errors.New("unexpected end of input")
+
+// EncodeI32 encodes a signed integer in its compact form
+func EncodeI32(n int32) []byte {
+	switch {
+	case -0x20 <= n && n < 0x20:
+		return []byte{byte(n)&valueMask | singleByteMode}
+	case -0x2000 <= n && n < 0x2000:
+		return []byte{byte(n>>8)&valueMask | twoByteMode, byte(n)}
+	case -0x20000000 <= n && n < 0x20000000:
+		return []byte{byte(n>>24)&valueMask | fourByteMode, byte(n >> 16), byte(n >> 8), byte(n)}
+	default:
+		return []byte{multiByteMode, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}
+	}
+}
+
+// DecodeI32 decodes a compact signed integer, returning the remaining bytes
+func DecodeI32(b []byte) (int32, []byte, error) {
+	if len(b) == 0 {
+		return 0, nil, ErrUnexpectedEnd
+	}
+	switch b[0] & modeMask {
+	case singleByteMode:
+		n := int32(b[0] & valueMask)
+		if n&0x20 != 0 {
+			n -= 0x40
+		}
+		return n, b[1:], nil
+	case twoByteMode:
+		if len(b) < 2 {
+			return 0, nil, ErrUnexpectedEnd
+		}
+		n := int32(b[0]&valueMask)<<8 | int32(b[1])
+		if n&0x2000 != 0 {
+			n -= 0x4000
+		}
+		return n, b[2:], nil
+	case fourByteMode:
+		if len(b) < 4 {
+			return 0, nil, ErrUnexpectedEnd
+		}
+		n := int32(b[0]&valueMask)<<24 | int32(b[1])<<16 | int32(b[2])<<8 | int32(b[3])
+		if n&0x20000000 != 0 {
+			n -= 0x40000000
+		}
+		return n, b[4:], nil
+	default:
+		size := int(b[0]&valueMask) + 4
+		if size != 4 {
+			return 0, nil, fmt.Errorf("expected a 32 bits integer, got %d bytes", size)
+		}
+		if len(b) < 1+size {
+			return 0, nil, ErrUnexpectedEnd
+		}
+		return int32(binary.BigEndian.Uint32(b[1:5])), b[5:], nil
+	}
+}
+
+// EncodeU256 encodes an unsigned integer, up to 256 bits, in its compact form
+func EncodeU256(n *big.Int) []byte {
+	if n.IsUint64() {
+		v := n.Uint64()
+		switch {
+		case v < 0x40:
+			return []byte{byte(v) | singleByteMode}
+		case v < 0x4000:
+			return []byte{byte(v>>8) | twoByteMode, byte(v)}
+		case v < 0x40000000:
+			return []byte{byte(v>>24) | fourByteMode, byte(v >> 16), byte(v >> 8), byte(v)}
+		}
+	}
+	data := n.Bytes()
+	return append([]byte{byte(len(data)-4) | multiByteMode}, data...)
+}
+
+// DecodeU256 decodes a compact unsigned integer, returning the remaining bytes
+func DecodeU256(b []byte) (*big.Int, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, ErrUnexpectedEnd
+	}
+	switch b[0] & modeMask {
+	case singleByteMode:
+		return new(big.Int).SetUint64(uint64(b[0] & valueMask)), b[1:], nil
+	case twoByteMode:
+		if len(b) < 2 {
+			return nil, nil, ErrUnexpectedEnd
+		}
+		return new(big.Int).SetUint64(uint64(b[0]&valueMask)<<8 | uint64(b[1])), b[2:], nil
+	case fourByteMode:
+		if len(b) < 4 {
+			return nil, nil, ErrUnexpectedEnd
+		}
+		v := uint64(b[0]&valueMask)<<24 | uint64(b[1])<<16 | uint64(b[2])<<8 | uint64(b[3])
+		return new(big.Int).SetUint64(v), b[4:], nil
+	default:
+		size := int(b[0]&valueMask) + 4
+		if size > 32 {
+			return nil, nil, fmt.Errorf("U256 overflow, got %d bytes", size)
+		}
+		if len(b) < 1+size {
+			return nil, nil, ErrUnexpectedEnd
+		}
+		return new(big.Int).SetBytes(b[1 : 1+size]), b[1+size:], nil
+	}
+}
+
+// EncodeBytes encodes a byte string, prefixed by its compact length
+func EncodeBytes(data []byte) []byte {
+	return append(EncodeI32(int32(len(data))), data...)
+}
+
+// DecodeBytes decodes a length-prefixed byte string, returning the remaining bytes
+func DecodeBytes(b []byte) ([]byte, []byte, error) {
+	size, rest, err := DecodeI32(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if size < 0 {
+		return nil, nil, fmt.Errorf("negative length %d", size)
+	}
+	if len(rest) < int(size) {
+		return nil, nil, ErrUnexpectedEnd
+	}
+	return rest[:size], rest[size:], nil
+}
@@ -0,0 +1,66 @@
+package serde
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+)
+
+func TestI32(t *testing.T) {
+	cases := map[int32]string{
+		0:           "00",
+		1:           "01",
+		-1:          "3f",
+		0x1f:        "1f",
+		-0x20:       "20",
+		0x20:        "4020",
+		-0x21:       "7fdf",
+		0x1fff:      "5fff",
+		0x2000:      "80002000",
+		-0x20000000: "a0000000",
+		0x7fffffff:  "c07fffffff",
+		-0x80000000: "c080000000",
+	}
+	for n, expected := range cases {
+		encoded := EncodeI32(n)
+		assert.Equal(t, expected, hex.EncodeToString(encoded), "encoding %d", n)
+		decoded, rest, err := DecodeI32(append(encoded, 0xff))
+		assert.Nil(t, err)
+		assert.Equal(t, n, decoded)
+		assert.Equal(t, []byte{0xff}, rest)
+	}
+	_, _, err := DecodeI32([]byte{0x80, 0x00})
+	assert.Equal(t, ErrUnexpectedEnd, err)
+}
+
+func TestU256(t *testing.T) {
+	oneALPH, _ := new(big.Int).SetString("1000000000000000000", 10)
+	cases := map[string]*big.Int{
+		"00":                 big.NewInt(0),
+		"3f":                 big.NewInt(0x3f),
+		"4040":               big.NewInt(0x40),
+		"80004000":           big.NewInt(0x4000),
+		"c040000000":         big.NewInt(0x40000000),
+		"c40de0b6b3a7640000": oneALPH,
+	}
+	for expected, n := range cases {
+		encoded := EncodeU256(n)
+		assert.Equal(t, expected, hex.EncodeToString(encoded), "encoding %s", n)
+		decoded, rest, err := DecodeU256(encoded)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, n.Cmp(decoded))
+		assert.Empty(t, rest)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	encoded := EncodeBytes([]byte{1, 2, 3})
+	assert.Equal(t, "03010203", hex.EncodeToString(encoded))
+	decoded, rest, err := DecodeBytes(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, decoded)
+	assert.Empty(t, rest)
+	_, _, err = DecodeBytes([]byte{0x03, 0x01})
+	assert.Equal(t, ErrUnexpectedEnd, err)
+}
@@ -0,0 +1,114 @@
+// Package signer signs transactions locally, with a secp256k1 private key held by the caller,
+// so that no key needs to be kept in a wallet of the node.
+//
+// It follows the signature scheme of the node: the 32 bytes of the transaction id are signed
+// as is (they are already a blake2b hash), with a deterministic RFC6979 nonce, and the signature
+// is the 64 bytes r || s, s being normalized to the lower half of the curve order.
+package signer
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/touilleio/alephium-go-client"
+)
+
+const (
+	HashLength      = 32
+	SignatureLength = 64
+)
+
+// Signer signs transactions with a private key
+type Signer struct {
+	privateKey *secp256k1.PrivateKey
+}
+
+// New creates a signer from a 32 bytes hex encoded private key
+func New(privateKey string) (*Signer, error) {
+	b, err := hex.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %v", err)
+	}
+	if len(b) != 32 {
+		return nil, fmt.Errorf("invalid private key length %d, expected 32", len(b))
+	}
+	key := secp256k1.PrivKeyFromBytes(b)
+	if key.Key.IsZero() {
+		return nil, fmt.Errorf("invalid private key, out of range")
+	}
+	return &Signer{privateKey: key}, nil
+}
+
+// Generate creates a signer with a new random private key
+func Generate() (*Signer, error) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{privateKey: key}, nil
+}
+
+// PrivateKey returns the hex encoded private key
+func (s *Signer) PrivateKey() string {
+	return hex.EncodeToString(s.privateKey.Serialize())
+}
+
+// PublicKey returns the hex encoded compressed public key, as expected by BuildTransaction
+func (s *Signer) PublicKey() string {
+	return hex.EncodeToString(s.privateKey.PubKey().SerializeCompressed())
+}
+
+// Sign signs the hex encoded hash and returns the hex encoded signature
+func (s *Signer) Sign(hash string) (string, error) {
+	h, err := decodeHash(hash)
+	if err != nil {
+		return "", err
+	}
+	// the compact signature is the recovery code followed by r and s, the later being canonical
+	compact := ecdsa.SignCompact(s.privateKey, h, true)
+	return hex.EncodeToString(compact[1:]), nil
+}
+
+// SignTransaction signs the id of the unsigned transaction
+func (s *Signer) SignTransaction(tx alephium.UnsignedTransaction) (string, error) {
+	return s.Sign(tx.TxId)
+}
+
+// Submit signs the unsigned transaction and submits it to the node
+func (s *Signer) Submit(ctx context.Context, client *alephium.Client, tx alephium.UnsignedTransaction) (alephium.Transaction, error) {
+	signature, err := s.SignTransaction(tx)
+	if err != nil {
+		return alephium.Transaction{}, err
+	}
+	return client.SubmitTransactionCtx(ctx, tx.UnsignedTx, signature)
+}
+
+// SignMultisig checks the id of the multisig transaction, signs it and adds the signature to it
+func (s *Signer) SignMultisig(tx *alephium.PartiallySignedTransaction) error {
+	if err := tx.CheckTxId(); err != nil {
+		return err
+	}
+	signature, err := s.Sign(tx.TxId)
+	if err != nil {
+		return err
+	}
+	return tx.AddSignature(s.PublicKey(), signature)
+}
+
+// Verify checks the hex encoded signature of the hash against the hex encoded public key
+func Verify(publicKey string, hash string, signature string) (bool, error) {
+	return alephium.VerifySignature(publicKey, hash, signature)
+}
+
+func decodeHash(hash string) ([]byte, error) {
+	h, err := hex.DecodeString(hash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hash: %v", err)
+	}
+	if len(h) != HashLength {
+		return nil, fmt.Errorf("invalid hash length %d, expected %d", len(h), HashLength)
+	}
+	return h, nil
+}
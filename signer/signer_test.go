@@ -0,0 +1,72 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/touilleio/alephium-go-client"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+
+	s, err := New("0000000000000000000000000000000000000000000000000000000000000001")
+	assert.Nil(t, err)
+	assert.Equal(t, "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", s.PublicKey())
+
+	// RFC6979 test vector, the hash being sha256("Satoshi Nakamoto")
+	hash := "a0dc65ffca799873cbea0ac274015b9526505daaaed385155425f7337704883e"
+	signature, err := s.Sign(hash)
+	assert.Nil(t, err)
+	assert.Equal(t, "934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8"+
+		"2442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5", signature)
+
+	ok, err := Verify(s.PublicKey(), hash, signature)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	other, err := Generate()
+	assert.Nil(t, err)
+	ok, err = Verify(other.PublicKey(), hash, signature)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	_, err = s.Sign("abcd")
+	assert.NotNil(t, err)
+	_, err = New("00")
+	assert.NotNil(t, err)
+	_, err = New("0000000000000000000000000000000000000000000000000000000000000000")
+	assert.NotNil(t, err)
+}
+
+func TestSubmit(t *testing.T) {
+
+	s, err := Generate()
+	assert.Nil(t, err)
+
+	var submitted alephium.SubmitTransactionBodyRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/transactions/submit", r.URL.Path)
+		_ = json.NewDecoder(r.Body).Decode(&submitted)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"txId":"bdaf9dc514ce7d34b6474b8ca10a3dfb93ba997cb9d5ff1ea724ebe2af48abe5","fromGroup":0,"toGroup":1}`))
+	}))
+	defer ts.Close()
+	client, err := alephium.NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	tx := alephium.UnsignedTransaction{
+		UnsignedTx: "00010203",
+		TxId:       "bdaf9dc514ce7d34b6474b8ca10a3dfb93ba997cb9d5ff1ea724ebe2af48abe5",
+	}
+	transaction, err := s.Submit(context.Background(), client, tx)
+	assert.Nil(t, err)
+	assert.Equal(t, tx.TxId, transaction.TransactionId)
+	assert.Equal(t, tx.UnsignedTx, submitted.UnsignedTx)
+
+	ok, err := Verify(s.PublicKey(), tx.TxId, submitted.Signature)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}